@@ -3,10 +3,13 @@ package config
 import (
     "encoding/json"
     "github.com/pkg/errors"
+    "html/template"
     "io"
     "io/ioutil"
     "log"
     "os"
+    "sync"
+    "time"
 )
 
 // SheetRange defines a range within a spreadsheet
@@ -15,6 +18,18 @@ type SheetRange struct {
     FirstColumn string
     LastColumn string
     FirstRow int
+    // Row with the header of each column. If 0, the row just before FirstRow.
+    HeaderRow int
+}
+
+// UserHeaders defines the header, in the spreadsheet, of each column of a user
+type UserHeaders struct {
+    JoinedMt string
+    Name string
+    TorneyCount string
+    Win string
+    Lose string
+    Draft string
 }
 
 type Config struct {
@@ -26,6 +41,20 @@ type Config struct {
     CredentialFile string
     // Path to a JSON file with the token to access Google's API
     TokenFile string
+    // Path to a service account's JSON key. If set, the spreadsheet is
+    // accessed as the service account, ignoring CredentialFile and TokenFile.
+    ServiceAccountFile string
+    // Paths to CSV (or TSV) exports of each sheet, keyed by the sheet's name.
+    // If set, the data is read from these files instead of from the
+    // spreadsheet, so no token is needed.
+    CsvFiles map[string]string
+    // Path to a XLSX (or ODS) copy of the whole spreadsheet. If set, the data
+    // is read from this file instead of from the spreadsheet (or from
+    // CsvFiles).
+    WorkbookFile string
+    // URL to which Google redirects after authorizing the server. Defaults to
+    // "http://localhost:<Port>/oauth/callback".
+    OAuthRedirectUrl string
     // ID of the MT Career spreadsheet
     MtCareerSpreasheet string
     // Range used to extract the number of entrants
@@ -34,7 +63,16 @@ type Config struct {
     UserInfo SheetRange
     // Range used to extract the entrants standings
     StandingsInfo SheetRange
-    // Index, in the spreadsheet, of the number of tournaments entered by the user
+    // Range with the name of each MT on its first row and the number of
+    // entrants of each MT on the row below it
+    MtEntrantsInfo SheetRange
+    // Header of each column in UserInfo, used to find the column of the
+    // fields whose index is -1
+    UserHeaders UserHeaders
+    // Index, in the spreadsheet, of the number of tournaments entered by the
+    // user. If -1, looked up by its header (as is every index below). Fields
+    // whose header can't be found aren't read, except for the name, which is
+    // required.
     JoinedMtIdx int
     // Index, in the spreadsheet, of the user's name
     NameIdx int
@@ -46,6 +84,9 @@ type Config struct {
     LoseIdx int
     // Index, in the spreadsheet, of the user's value(?) in draft points
     DraftIdx int
+    // Whether blank cells in a user's row are read as zero (or as an empty
+    // text), instead of failing to parse the user
+    LenientRows bool
     // Path to a CSS file used to override the default CSS
     CssFile string
     // Style sheet for the player page
@@ -54,15 +95,59 @@ type Config struct {
     TemplateFile string
     // Template for the player page
     templateData []byte
+    // Path to a HTML-template file used to override the default match page template
+    MatchTemplateFile string
+    // Template for the match page
+    matchTemplateData []byte
+    // Path to a SVG-template file used to override the default SVG title card
+    SvgTemplateFile string
+    // Template for the SVG title card
+    svgTemplateData []byte
+    // When each of the custom files was last modified
+    modTimes map[string]time.Time
+    // Path to a directory with every theme, each within its own sub-directory
+    ThemesDir string
+    // Time, in seconds, between checks for modified CSS and template files
+    // (including themes), and for modified local copies of the spreadsheet.
+    // 0 disables reloading them.
+    ReloadInterval int
+    // Time, in seconds, between checks for whether the OAuth token was
+    // revoked. 0 disables the check, so a revoked token is only detected
+    // when the spreadsheet is accessed.
+    TokenCheckInterval int
     // URI of the service within the server. Mostly used to set the path to the CSS file.
     ServiceUri string
+    // Time, in seconds, that a downloaded player is considered up-to-date. 0
+    // disables expiration.
+    CacheTTL int
+    // Time, in seconds, that an expired player is still served while it's
+    // downloaded again in the background. After that, the player is
+    // downloaded before being served. 0 serves expired players indefinitely.
+    CacheMaxStale int
+    // Username required to access the admin pages
+    AdminUser string
+    // Password required to access the admin pages
+    AdminPassword string
+    // Token that may be sent, instead of the username and password, to access
+    // the admin pages (as "Authorization: Bearer <token>")
+    AdminToken string
 }
 
 // Store the loaded configuration
 var config Config
+// lock synchronizes access to the configuration, since the custom files may
+// be reloaded while the server is running
+var lock sync.RWMutex
+// loaded is set as soon as any configuration gets loaded
+var loaded bool
+// rejected maps every custom file to when it was last modified, if it was
+// rejected by the last reload, so it's only reloaded once it changes again
+var rejected map[string]time.Time
 
 // Get a copy of the configuration
 func Get() Config {
+    lock.RLock()
+    defer lock.RUnlock()
     return config
 }
 
@@ -86,6 +171,34 @@ func (c Config) PageTemplate(fallback string) string {
     return fallback
 }
 
+// CacheExpiration returns how long a downloaded player is considered
+// up-to-date, or 0 if it never expires
+func (c Config) CacheExpiration() time.Duration {
+    return time.Duration(c.CacheTTL) * time.Second
+}
+
+// CacheStaleLimit returns for how long an expired player may still be served,
+// or 0 if there's no limit
+func (c Config) CacheStaleLimit() time.Duration {
+    return time.Duration(c.CacheMaxStale) * time.Second
+}
+
+// MatchPageTemplate returns the match page template or the supplied fallback
+func (c Config) MatchPageTemplate(fallback string) string {
+    if len(c.matchTemplateData) != 0 {
+        return string(c.matchTemplateData)
+    }
+    return fallback
+}
+
+// SvgTemplate returns the SVG title card template or the supplied fallback
+func (c Config) SvgTemplate(fallback string) string {
+    if len(c.svgTemplateData) != 0 {
+        return string(c.svgTemplateData)
+    }
+    return fallback
+}
+
 // Retrieve the default configurations
 func GetDefault() Config {
     return Config {
@@ -93,6 +206,10 @@ func GetDefault() Config {
         TwitchClientID: "",
         CredentialFile: "credentials.json",
         TokenFile: "token.json",
+        ServiceAccountFile: "",
+        CsvFiles: map[string]string{},
+        WorkbookFile: "",
+        OAuthRedirectUrl: "",
         MtCareerSpreasheet: "1DWYq3T1w8u1N0CWWJ72tqQRv67c1eY098u0wyuiMEmA",
         TourneyInfo: SheetRange {
             SheetName: "STATS",
@@ -112,43 +229,182 @@ func GetDefault() Config {
             LastColumn: "S",
             FirstRow: 1,
         },
-        JoinedMtIdx: 0,
-        NameIdx: 1,
-        TorneyCountIdx: 2,
-        WinIdx: 3,
-        LoseIdx: 4,
-        DraftIdx: 7,
+        MtEntrantsInfo: SheetRange {
+            SheetName: "STATS",
+            FirstColumn: "C",
+            LastColumn: "Q",
+            FirstRow: 2,
+        },
+        UserHeaders: UserHeaders {
+            JoinedMt: "First MT",
+            Name: "Name",
+            TorneyCount: "MTs",
+            Win: "Wins",
+            Lose: "Losses",
+            Draft: "Draft Points",
+        },
+        JoinedMtIdx: -1,
+        NameIdx: -1,
+        TorneyCountIdx: -1,
+        WinIdx: -1,
+        LoseIdx: -1,
+        DraftIdx: -1,
+        LenientRows: false,
+        CacheTTL: 600,
+        CacheMaxStale: 3600,
+        ReloadInterval: 5,
+        TokenCheckInterval: 3600,
+        AdminUser: "admin",
+        AdminPassword: "",
+        AdminToken: "",
     }
 }
 
-// Load the supplied configuration
-func LoadConfig(customConfig Config) error {
-    var err error
+// customFile is a file that overrides some of the default data
+type customFile struct {
+    // path to the file
+    path string
+    // data where the file's content is stored
+    data *[]byte
+    // desc describes the file in error messages
+    desc string
+}
+
+// customFiles lists every file that overrides some of the default data
+func (c *Config) customFiles() []customFile {
+    return []customFile {
+        {c.CssFile, &c.cssData, "custom CSS file"},
+        {c.TemplateFile, &c.templateData, "custom template file"},
+        {c.MatchTemplateFile, &c.matchTemplateData, "custom match template file"},
+        {c.SvgTemplateFile, &c.svgTemplateData, "custom SVG template file"},
+    }
+}
+
+// readFiles reads every custom file, storing when each was last modified
+func (c *Config) readFiles() error {
+    c.modTimes = map[string]time.Time{}
+
+    for _, f := range c.customFiles() {
+        if f.path == "" {
+            continue
+        }
 
-    if customConfig.CssFile != "" {
-        customConfig.cssData, err = ioutil.ReadFile(customConfig.CssFile)
+        st, err := os.Stat(f.path)
+        if err == nil {
+            *f.data, err = ioutil.ReadFile(f.path)
+        }
         if err != nil {
-            return errors.Wrap(err, "Failed to read the custom CSS file")
+            return errors.Wrap(err, "Failed to read the " + f.desc)
         }
+        c.modTimes[f.path] = st.ModTime()
     }
 
-    if customConfig.TemplateFile != "" {
-        customConfig.templateData, err = ioutil.ReadFile(customConfig.TemplateFile)
+    return nil
+}
+
+// parseTemplates checks that every custom template file parses
+func (c *Config) parseTemplates() error {
+    for _, f := range []customFile {
+        {c.TemplateFile, &c.templateData, "custom template file"},
+        {c.MatchTemplateFile, &c.matchTemplateData, "custom match template file"},
+        {c.SvgTemplateFile, &c.svgTemplateData, "custom SVG template file"},
+    } {
+        if len(*f.data) == 0 {
+            continue
+        }
+        _, err := template.New(f.path).Parse(string(*f.data))
+        if err != nil {
+            return errors.Wrap(err, "Failed to parse the " + f.desc)
+        }
+    }
+    return nil
+}
+
+// sameTimes checks whether both lists of files are exactly the same
+func sameTimes(a, b map[string]time.Time) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for path, t := range a {
+        if other, ok := b[path]; !ok || !other.Equal(t) {
+            return false
+        }
+    }
+    return true
+}
+
+// ReloadFiles reads every custom file (e.g., CssFile and TemplateFile) once
+// again, if any of them got modified since they were last read. Returns
+// whether the files were reloaded. On failure (including templates that fail
+// to parse), the previous files are kept, and the modified files aren't read
+// again until they change once more.
+func ReloadFiles() (bool, error) {
+    c := Get()
+
+    times := map[string]time.Time{}
+    for _, f := range c.customFiles() {
+        if f.path == "" {
+            continue
+        }
+
+        st, err := os.Stat(f.path)
         if err != nil {
-            return errors.Wrap(err, "Failed to read the custom template file")
+            return false, errors.Wrap(err, "Failed to check the " + f.desc)
         }
+        times[f.path] = st.ModTime()
     }
 
+    lock.RLock()
+    skip := sameTimes(times, c.modTimes) || sameTimes(times, rejected)
+    lock.RUnlock()
+    if skip {
+        return false, nil
+    }
+
+    err := c.readFiles()
+    if err == nil {
+        err = c.parseTemplates()
+    }
+
+    lock.Lock()
+    defer lock.Unlock()
+
+    if err != nil {
+        rejected = times
+        return false, err
+    }
+    rejected = nil
+    config = c
+    return true, nil
+}
+
+// Load the supplied configuration
+func LoadConfig(customConfig Config) error {
+    err := customConfig.readFiles()
+    if err != nil {
+        return err
+    }
+
+    lock.Lock()
     config = customConfig
+    loaded = true
+    rejected = nil
+    lock.Unlock()
     return nil
 }
 
+// Loaded checks whether any configuration has been loaded
+func Loaded() bool {
+    lock.RLock()
+    defer lock.RUnlock()
+    return loaded
+}
+
 // Load the supplied configuration on file, or the default configuration
 func Load(path string) error {
     if path == "" {
         log.Print("Using the default configuration...")
-        config = GetDefault()
-        return nil
+        return LoadConfig(GetDefault())
     }
 
     log.Printf("Loading the configuration from '%s'...", path)
@@ -159,7 +415,8 @@ func Load(path string) error {
     defer f.Close()
     dec := json.NewDecoder(f)
 
-    var _config Config
+    // Start from the defaults, so fields missing from the file keep them
+    _config := GetDefault()
     err = dec.Decode(&_config)
     if err != nil {
         return errors.Wrap(err, "Failed to decode config JSON")
@@ -0,0 +1,88 @@
+package config
+
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+func TestLoadKeepsDefaults(t *testing.T) {
+    prev := Get()
+    t.Cleanup(func() {
+        LoadConfig(prev)
+    })
+
+    path := filepath.Join(t.TempDir(), "config.json")
+    err := ioutil.WriteFile(path, []byte(`{"port": 9000, "tourneyInfo": {"firstRow": 20}}`), 0600)
+    if err != nil {
+        t.Fatalf("Failed to write the configuration: %+v", err)
+    }
+    err = Load(path)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+
+    def := GetDefault()
+    cfg := Get()
+    if cfg.Port != 9000 || cfg.TourneyInfo.FirstRow != 20 {
+        t.Errorf("Failed to load the configured fields: %+v", cfg)
+    }
+    if cfg.CacheTTL != def.CacheTTL || cfg.ReloadInterval != def.ReloadInterval || cfg.AdminUser != def.AdminUser {
+        t.Errorf("Expected missing fields to keep their defaults: %+v", cfg)
+    }
+    if cfg.MtEntrantsInfo != def.MtEntrantsInfo || cfg.TourneyInfo.SheetName != def.TourneyInfo.SheetName {
+        t.Errorf("Expected missing ranges to keep their defaults: %+v", cfg)
+    }
+}
+
+func TestReloadKeepsValidTemplates(t *testing.T) {
+    prev := Get()
+    t.Cleanup(func() {
+        LoadConfig(prev)
+    })
+
+    path := filepath.Join(t.TempDir(), "template.html")
+    err := ioutil.WriteFile(path, []byte(`<p>{{.Username}}</p>`), 0600)
+    if err != nil {
+        t.Fatalf("Failed to write the template: %+v", err)
+    }
+    cfg := GetDefault()
+    cfg.TemplateFile = path
+    err = LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+
+    err = ioutil.WriteFile(path, []byte(`<p>{{.Username</p>`), 0600)
+    if err != nil {
+        t.Fatalf("Failed to write the template: %+v", err)
+    }
+    mod := time.Now().Add(time.Minute)
+    os.Chtimes(path, mod, mod)
+
+    changed, err := ReloadFiles()
+    if changed || err == nil {
+        t.Errorf("Expected the broken template to be rejected, got %v (%+v)", changed, err)
+    }
+    if tmpl := Get().PageTemplate(""); tmpl != `<p>{{.Username}}</p>` {
+        t.Errorf("Expected the previous template to be kept, got '%s'", tmpl)
+    }
+    if changed, err := ReloadFiles(); changed || err != nil {
+        t.Errorf("Expected the rejected template to not be read again, got %v (%+v)", changed, err)
+    }
+
+    err = ioutil.WriteFile(path, []byte(`<p>{{.Channel}}</p>`), 0600)
+    if err != nil {
+        t.Fatalf("Failed to write the template: %+v", err)
+    }
+    mod = mod.Add(time.Minute)
+    os.Chtimes(path, mod, mod)
+    if changed, err := ReloadFiles(); !changed || err != nil {
+        t.Errorf("Expected the fixed template to be reloaded, got %v (%+v)", changed, err)
+    }
+    if tmpl := Get().PageTemplate(""); tmpl != `<p>{{.Channel}}</p>` {
+        t.Errorf("Expected the fixed template to be loaded, got '%s'", tmpl)
+    }
+}
@@ -0,0 +1,264 @@
+// Package metrics implements the few metric types used by the server,
+// exposing them in Prometheus' text format.
+package metrics
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// Kinds of metrics, as reported in the "# TYPE" line
+const (
+    kindCounter = "counter"
+    kindGauge = "gauge"
+    kindHistogram = "histogram"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, used by latency histograms
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// series stores the values of a metric for a given set of labels
+type series struct {
+    // labels formatted as in the text format (e.g., `route="vs",code="200"`)
+    labels string
+    // value of counters
+    value float64
+    // counts of observations in each histogram bucket (not cumulative)
+    counts []uint64
+    // sum of every observation in a histogram
+    sum float64
+    // count of observations in a histogram
+    count uint64
+}
+
+// family groups every series of a metric
+type family struct {
+    name string
+    help string
+    kind string
+    // labelNames lists the name of every label, in order
+    labelNames []string
+    // buckets are the upper bounds of a histogram's buckets
+    buckets []float64
+    // series maps the formatted labels to their values
+    series map[string]*series
+    // gauge retrieves the value of a gauge, and whether it's available
+    gauge func() (float64, bool)
+}
+
+// Registry stores every metric exposed by the server
+type Registry struct {
+    sync.Mutex
+    // families lists every metric, in the order they were registered
+    families []*family
+}
+
+// Default is the registry used by the server
+var Default = &Registry{}
+
+// Counter is a value that only ever increases
+type Counter struct {
+    r *Registry
+    f *family
+}
+
+// Histogram counts observations (e.g., latencies) in buckets
+type Histogram struct {
+    r *Registry
+    f *family
+}
+
+// register a new metric family
+func (r *Registry) register(f *family) {
+    r.Lock()
+    defer r.Unlock()
+
+    f.series = map[string]*series{}
+    r.families = append(r.families, f)
+}
+
+// NewCounter registers a counter, partitioned by the supplied labels
+func (r *Registry) NewCounter(name, help string, labelNames ...string) *Counter {
+    f := &family {
+        name: name,
+        help: help,
+        kind: kindCounter,
+        labelNames: labelNames,
+    }
+    r.register(f)
+    return &Counter{r, f}
+}
+
+// NewHistogram registers a histogram, partitioned by the supplied labels
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+    f := &family {
+        name: name,
+        help: help,
+        kind: kindHistogram,
+        labelNames: labelNames,
+        buckets: buckets,
+    }
+    r.register(f)
+    return &Histogram{r, f}
+}
+
+// NewGaugeFunc registers a gauge whose value is retrieved whenever the
+// metrics are written. The gauge is omitted while fn reports it as
+// unavailable.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() (float64, bool)) {
+    r.register(&family {
+        name: name,
+        help: help,
+        kind: kindGauge,
+        gauge: fn,
+    })
+}
+
+// escapeLabel escapes a label value as required by the text format
+func escapeLabel(val string) string {
+    val = strings.Replace(val, `\`, `\\`, -1)
+    val = strings.Replace(val, "\n", `\n`, -1)
+    return strings.Replace(val, `"`, `\"`, -1)
+}
+
+// get the series for the supplied label values, creating it if needed. Must
+// be called with the lock held.
+func (f *family) get(labelValues []string) *series {
+    var parts []string
+    for i, name := range f.labelNames {
+        var val string
+        if i < len(labelValues) {
+            val = labelValues[i]
+        }
+        parts = append(parts, fmt.Sprintf(`%s="%s"`, name, escapeLabel(val)))
+    }
+    labels := strings.Join(parts, ",")
+
+    s, ok := f.series[labels]
+    if !ok {
+        s = &series {
+            labels: labels,
+            counts: make([]uint64, len(f.buckets)),
+        }
+        f.series[labels] = s
+    }
+    return s
+}
+
+// Add v to the counter with the supplied label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+    c.r.Lock()
+    defer c.r.Unlock()
+    c.f.get(labelValues).value += v
+}
+
+// Inc increments the counter with the supplied label values
+func (c *Counter) Inc(labelValues ...string) {
+    c.Add(1, labelValues...)
+}
+
+// Observe a value in the histogram with the supplied label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+    h.r.Lock()
+    defer h.r.Unlock()
+
+    s := h.f.get(labelValues)
+    for i, bound := range h.f.buckets {
+        if v <= bound {
+            s.counts[i]++
+            break
+        }
+    }
+    s.sum += v
+    s.count++
+}
+
+// formatFloat formats a value as expected by the text format
+func formatFloat(v float64) string {
+    switch {
+    case math.IsInf(v, 1):
+        return "+Inf"
+    case math.IsInf(v, -1):
+        return "-Inf"
+    case math.IsNaN(v):
+        return "NaN"
+    }
+    return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// withLabels formats a sample's name, appending extra labels to the series'
+func withLabels(name, labels, extra string) string {
+    switch {
+    case labels == "" && extra == "":
+        return name
+    case labels == "":
+        return name + "{" + extra + "}"
+    case extra == "":
+        return name + "{" + labels + "}"
+    }
+    return name + "{" + labels + "," + extra + "}"
+}
+
+// WriteText writes every metric in Prometheus' text format
+func (r *Registry) WriteText(w io.Writer) error {
+    // Gauges are retrieved outside the lock, so they may take their time
+    r.Lock()
+    families := append([]*family(nil), r.families...)
+    r.Unlock()
+    gauges := map[*family]float64{}
+    for _, f := range families {
+        if f.gauge != nil {
+            if v, ok := f.gauge(); ok {
+                gauges[f] = v
+            }
+        }
+    }
+
+    r.Lock()
+    defer r.Unlock()
+
+    buf := bufio.NewWriter(w)
+    for _, f := range families {
+        fmt.Fprintf(buf, "# HELP %s %s\n", f.name, f.help)
+        fmt.Fprintf(buf, "# TYPE %s %s\n", f.name, f.kind)
+
+        if f.gauge != nil {
+            if v, ok := gauges[f]; ok {
+                fmt.Fprintf(buf, "%s %s\n", f.name, formatFloat(v))
+            }
+            continue
+        }
+
+        keys := make([]string, 0, len(f.series))
+        for k := range f.series {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+
+        for _, k := range keys {
+            s := f.series[k]
+            if f.kind == kindCounter {
+                fmt.Fprintf(buf, "%s %s\n", withLabels(f.name, s.labels, ""), formatFloat(s.value))
+                continue
+            }
+
+            var cumulative uint64
+            for i, bound := range f.buckets {
+                cumulative += s.counts[i]
+                le := fmt.Sprintf(`le="%s"`, formatFloat(bound))
+                fmt.Fprintf(buf, "%s %d\n", withLabels(f.name + "_bucket", s.labels, le), cumulative)
+            }
+            fmt.Fprintf(buf, "%s %d\n", withLabels(f.name + "_bucket", s.labels, `le="+Inf"`), s.count)
+            fmt.Fprintf(buf, "%s %s\n", withLabels(f.name + "_sum", s.labels, ""), formatFloat(s.sum))
+            fmt.Fprintf(buf, "%s %d\n", withLabels(f.name + "_count", s.labels, ""), s.count)
+        }
+    }
+
+    return buf.Flush()
+}
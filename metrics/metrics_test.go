@@ -0,0 +1,53 @@
+package metrics
+
+import (
+    "bytes"
+    "testing"
+)
+
+func TestWriteText(t *testing.T) {
+    r := &Registry{}
+    c := r.NewCounter("test_requests_total", "Requests.", "route", "code")
+    h := r.NewHistogram("test_duration_seconds", "Latency.", []float64{0.1, 1}, "route")
+    r.NewGaugeFunc("test_age_seconds", "Age.", func() (float64, bool) {
+        return 42.5, true
+    })
+    r.NewGaugeFunc("test_missing", "Missing.", func() (float64, bool) {
+        return 0, false
+    })
+
+    c.Inc("vs", "200")
+    c.Add(2, "player", "404")
+    c.Inc("say \"hi\"\n", "200")
+    h.Observe(0.05, "vs")
+    h.Observe(0.5, "vs")
+    h.Observe(3, "vs")
+
+    var buf bytes.Buffer
+    err := r.WriteText(&buf)
+    if err != nil {
+        t.Fatalf("Failed to write the metrics: %+v", err)
+    }
+
+    expected := `# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{route="player",code="404"} 2
+test_requests_total{route="say \"hi\"\n",code="200"} 1
+test_requests_total{route="vs",code="200"} 1
+# HELP test_duration_seconds Latency.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="vs",le="0.1"} 1
+test_duration_seconds_bucket{route="vs",le="1"} 2
+test_duration_seconds_bucket{route="vs",le="+Inf"} 3
+test_duration_seconds_sum{route="vs"} 3.55
+test_duration_seconds_count{route="vs"} 3
+# HELP test_age_seconds Age.
+# TYPE test_age_seconds gauge
+test_age_seconds 42.5
+# HELP test_missing Missing.
+# TYPE test_missing gauge
+`
+    if got := buf.String(); got != expected {
+        t.Errorf("Unexpected metrics:\n%s\nExpected:\n%s", got, expected)
+    }
+}
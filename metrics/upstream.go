@@ -0,0 +1,31 @@
+package metrics
+
+import (
+    "time"
+)
+
+// Upstream services accessed by the server
+const (
+    SRL = "srl"
+    Twitch = "twitch"
+    Sheets = "sheets"
+)
+
+var (
+    upstreamRequests = Default.NewCounter("mttc_upstream_requests_total",
+            "Requests sent to upstream services.", "service")
+    upstreamErrors = Default.NewCounter("mttc_upstream_errors_total",
+            "Requests to upstream services that failed.", "service")
+    upstreamDuration = Default.NewHistogram("mttc_upstream_request_duration_seconds",
+            "Latency of requests to upstream services.", DefaultBuckets, "service")
+)
+
+// Upstream records a request to an upstream service, started at start and
+// finished with err
+func Upstream(service string, start time.Time, err error) {
+    upstreamRequests.Inc(service)
+    if err != nil {
+        upstreamErrors.Inc(service)
+    }
+    upstreamDuration.Observe(time.Since(start).Seconds(), service)
+}
@@ -0,0 +1,140 @@
+package mtcareers
+
+import (
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "strings"
+)
+
+// userColumns maps each field of a User to its column in the UserInfo range.
+// Columns that couldn't be found are set to -1, and their fields aren't read.
+type userColumns struct {
+    joinedMt int
+    name int
+    torneyCount int
+    win int
+    lose int
+    draft int
+}
+
+// userField describes how a field of a User is mapped to its column
+type userField struct {
+    // idx is the column overridden in the configuration, or -1
+    idx int
+    // header is the text, in the header row, of the field's column
+    header string
+    // col receives the field's column
+    col *int
+}
+
+// fields lists every field of a User, alongside their configured column
+func (cols *userColumns) fields() []userField {
+    cfg := config.Get()
+    return []userField {
+        {cfg.JoinedMtIdx, cfg.UserHeaders.JoinedMt, &cols.joinedMt},
+        {cfg.NameIdx, cfg.UserHeaders.Name, &cols.name},
+        {cfg.TorneyCountIdx, cfg.UserHeaders.TorneyCount, &cols.torneyCount},
+        {cfg.WinIdx, cfg.UserHeaders.Win, &cols.win},
+        {cfg.LoseIdx, cfg.UserHeaders.Lose, &cols.lose},
+        {cfg.DraftIdx, cfg.UserHeaders.Draft, &cols.draft},
+    }
+}
+
+// needsHeader checks whether any column must be looked up by its header
+func needsHeader() bool {
+    var cols userColumns
+    for _, f := range cols.fields() {
+        if f.idx < 0 {
+            return true
+        }
+    }
+    return false
+}
+
+// resolveColumns maps each field of a User to its column, either as
+// overridden in the configuration or by looking up its header (ignoring
+// case and surrounding spaces). Returns the headers that couldn't be found.
+func resolveColumns(header []interface{}) (cols userColumns, missing []string) {
+    for _, f := range cols.fields() {
+        *f.col = f.idx
+        if f.idx >= 0 {
+            continue
+        }
+
+        want := strings.ToLower(strings.TrimSpace(f.header))
+        for i, cell := range header {
+            if want != "" && strings.ToLower(strings.TrimSpace(colToStr(cell))) == want {
+                *f.col = i
+                break
+            }
+        }
+        if *f.col < 0 {
+            missing = append(missing, f.header)
+        }
+    }
+    return
+}
+
+// headerRow retrieves the row with the headers of a range: either as
+// configured, or the row just before the range
+func headerRow(r config.SheetRange) int {
+    if r.HeaderRow > 0 {
+        return r.HeaderRow
+    }
+    return r.FirstRow - 1
+}
+
+// loadColumns reads the header row of the UserInfo range, mapping each field
+// of a User to its column. Returns the headers that couldn't be found, whose
+// fields aren't read. Since users are looked up by their name, it fails if
+// the name's column can't be found.
+func (s *Sheet) loadColumns() (userColumns, []string, error) {
+    var header []interface{}
+
+    info := config.Get().UserInfo
+    if row := headerRow(info); needsHeader() && row > 0 {
+        r := info
+        r.FirstRow = row
+        values, err := s.getRange(r, row)
+        if err != nil {
+            return userColumns{}, nil, errors.Wrap(err, "Unable to retrieve the headers from sheet")
+        }
+        if len(values) > 0 {
+            header = values[0]
+        }
+    }
+
+    cols, missing := resolveColumns(header)
+    if cols.name < 0 {
+        return userColumns{}, nil, errors.Wrap(missingHeadersError(missing), "Unable to look up users: unknown column for Username")
+    }
+    return cols, missing, nil
+}
+
+// missingHeadersError describes the headers that couldn't be found
+func missingHeadersError(missing []string) error {
+    info := config.Get().UserInfo
+    return errors.Errorf("Missing the headers %q in the row %d of the sheet '%s', so their fields aren't read: fix the sheet, or set either the headers (userHeaders) or the indexes (e.g., nameIdx) in the configuration",
+            missing, headerRow(info), info.SheetName)
+}
+
+// CheckHeaders looks up the column of each field in the header row of the
+// UserInfo range, reporting any header that's missing
+func CheckHeaders() error {
+    sh, err := GetProvider()
+    if err != nil {
+        return err
+    }
+    s, ok := sh.(*Sheet)
+    if !ok {
+        return nil
+    }
+
+    _, missing, err := s.loadColumns()
+    if err != nil {
+        return err
+    } else if len(missing) > 0 {
+        return missingHeadersError(missing)
+    }
+    return nil
+}
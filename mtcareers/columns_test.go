@@ -0,0 +1,113 @@
+package mtcareers
+
+import (
+    "github.com/SirGFM/MTTitleCard/config"
+    "strings"
+    "testing"
+)
+
+func TestResolveColumns(t *testing.T) {
+    cfg := config.GetDefault()
+    cfg.DraftIdx = 2
+    err := config.LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+
+    header := []interface{} {" name ", "WINS", "Draft Points", "First MT", "Losses"}
+    cols, missing := resolveColumns(header)
+    expected := userColumns {
+        joinedMt: 3,
+        name: 0,
+        torneyCount: -1,
+        win: 1,
+        lose: 4,
+        draft: 2,
+    }
+    if cols != expected {
+        t.Errorf("Expected columns %+v, got %+v", expected, cols)
+    }
+    if len(missing) != 1 || missing[0] != "MTs" {
+        t.Errorf("Expected only 'MTs' to be missing, got %q", missing)
+    }
+
+    // Fields without a column aren't read
+    rows := [][]interface{} {{"Alice", "10", "12.5", "MT1", "4"}}
+    u, err := rowToUser(newRowCells(rows, cfg.UserInfo, 0), cols)
+    if err != nil || u.TourneyCount != 0 || u.WinCount != 10 || u.FirstMT != "MT1" {
+        t.Errorf("Expected the missing column to be left as zero, got %+v (%+v)", u, err)
+    }
+}
+
+func TestMissingHeaders(t *testing.T) {
+    setupLocal(t)
+
+    s, err := GetProvider()
+    if err != nil {
+        t.Fatalf("Failed to get the provider: %+v", err)
+    }
+    err = s.GetTourneyInfo()
+    if err != nil {
+        t.Fatalf("Failed to get the tourney info: %+v", err)
+    }
+    cfg := config.Get()
+    cfg.UserHeaders.Win = "Victories"
+    err = config.LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+    Invalidate()
+
+    err = CheckHeaders()
+    if err == nil || !strings.Contains(err.Error(), `"Victories"`) {
+        t.Errorf("Expected the missing header to be reported, got %+v", err)
+    }
+    // Another column isn't read in place of the missing one
+    if u, err := s.GetUserInfo("Alice"); err != nil || u.WinCount != 0 || u.LoseCount != 4 {
+        t.Errorf("Expected the wins to not be read, got %+v (%+v)", u, err)
+    }
+
+    cfg.WinIdx = 3
+    err = config.LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+    Invalidate()
+    if err = CheckHeaders(); err != nil {
+        t.Errorf("Expected the index to override the header: %+v", err)
+    }
+    if u, err := s.GetUserInfo("Alice"); err != nil || u.WinCount != 10 {
+        t.Errorf("Failed to get the user: %+v (%+v)", u, err)
+    }
+}
+
+func TestMissingNameHeader(t *testing.T) {
+    setupLocal(t)
+
+    s, err := GetProvider()
+    if err != nil {
+        t.Fatalf("Failed to get the provider: %+v", err)
+    }
+    err = s.GetTourneyInfo()
+    if err != nil {
+        t.Fatalf("Failed to get the tourney info: %+v", err)
+    }
+    cfg := config.Get()
+    cfg.UserHeaders.Name = "Player"
+    err = config.LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+    Invalidate()
+
+    err = CheckHeaders()
+    if err == nil || !strings.Contains(err.Error(), `"Player"`) {
+        t.Errorf("Expected the missing header to be reported, got %+v", err)
+    }
+    if _, err := s.GetUserInfo("Alice"); err == nil || !strings.Contains(err.Error(), "Username") {
+        t.Errorf("Expected users to not be looked up without their name, got %+v", err)
+    }
+    if _, err := s.GetAllUsers(); err == nil {
+        t.Errorf("Expected users to not be listed without their name")
+    }
+}
@@ -5,8 +5,11 @@ import (
     "fmt"
     "github.com/pkg/errors"
     "github.com/SirGFM/MTTitleCard/config"
+    "log"
     "strconv"
     "strings"
+    "sync"
+    "time"
 )
 
 // baseRange formats the lookup string for retrieving a range from a
@@ -17,15 +20,36 @@ import (
 //     fmt.Sprintf(baseRange, "TEST", "A", 5, "D", 20)
 const baseRange = "%s!%s%d:%s%d"
 
-// _tourneyCache stores the downloaded tournament info spreadsheet
-var _tourneyCache [][]interface{} = nil
-// _standingsCache stores the downloaded standings spreadsheet
-var _standingsCache [][]interface{} = nil
+// sheetCache stores the downloaded spreadsheet. Every slice is replaced
+// (instead of modified) when the spreadsheet is downloaded again, so they may
+// be used outside the lock.
+type sheetCache struct {
+    sync.Mutex
+    // tourney stores the downloaded tournament info spreadsheet
+    tourney [][]interface{}
+    // columns maps each field of a User to its column in tourney
+    columns userColumns
+    // standings stores the downloaded standings spreadsheet
+    standings [][]interface{}
+    // idxToPlace converts an index in the standings row into a tournament
+    // placement. The first two values are initialized to zero to skip the
+    // name and MT count columns.
+    idxToPlace []int
+    // entrants stores the downloaded number of entrants of each MT
+    entrants [][]interface{}
+    // updated is when the spreadsheet was last downloaded
+    updated time.Time
+}
+
+// _cache stores the downloaded spreadsheet
+var _cache sheetCache
+
+// NoPlacement is the highest position of users that haven't placed in any
+// tournament yet
+const NoPlacement = 9999
 
-// _idxToPlace converts an index in the standings row into a tournament
-// placement. The first two values are initialized to zero to skip the name and
-// MT count columns.
-var _idxToPlace []int = []int{0, 0}
+// ErrUserNotFound indicates that the user isn't in the spreadsheet
+var ErrUserNotFound error = goErrors.New("User not found")
 
 // errNoPlacing indicates that the user still doesn't have a best placement, as
 // this is most likely their first MT
@@ -57,28 +81,27 @@ func (s *Sheet) GetTourneyInfo() error {
         return nil
     }
 
-    _range := fmt.Sprintf(baseRange,
-        config.Get().TourneyInfo.SheetName,
-        config.Get().TourneyInfo.FirstColumn,
-        config.Get().TourneyInfo.FirstRow,
-        config.Get().TourneyInfo.LastColumn,
-        config.Get().TourneyInfo.FirstRow+1)
-    resp, err := s.srv.Spreadsheets.Values.Get(s.id, _range).Do()
+    info := config.Get().TourneyInfo
+    values, err := s.getRange(info, info.FirstRow+1)
     if err != nil {
         return errors.Wrap(err, "Failed to get the number of entrants: Unable to retrieve data from sheet")
     }
 
-    if len(resp.Values) == 0 {
+    if len(values) == 0 {
         return errors.New("Failed to get the number of entrants: No data found")
     } else {
-        row := resp.Values[0]
-        s.TotalEntrants, err = cellToInt(row[0])
+        // The number of entrants is required, regardless of LenientRows
+        rc := rowCells {
+            row: values[0],
+            r: info,
+        }
+        s.TotalEntrants, err = rc.integer(0, "TotalEntrants")
         if err != nil {
-            return errors.Wrap(err, "Failed to parse TotalEntrants from sheet")
+            return err
         }
-        s.LatestEntrants, err = cellToInt(row[len(row)-1])
+        s.LatestEntrants, err = rc.integer(len(rc.row)-1, "LatestEntrants")
         if err != nil {
-            return errors.Wrap(err, "Failed to parse LatestEntrants from sheet")
+            return err
         }
     }
 
@@ -86,38 +109,44 @@ func (s *Sheet) GetTourneyInfo() error {
 }
 
 // rowToUser convert a row, retrieved from the spreadsheet, into a User
-func rowToUser(row []interface{}) (u User, err error) {
-    u.Username, err = cellToStr(row[config.Get().NameIdx])
+func rowToUser(rc rowCells, cols userColumns) (u User, err error) {
+    // Users are looked up by their name, so it's required even on lenient rows
+    required := rc
+    required.lenient = false
+    u.Username, err = required.str(cols.name, "Username")
     if err != nil {
-        err = errors.Wrap(err, "Failed to parse Username from sheet")
         return
     }
-    u.FirstMT, err = cellToStr(row[config.Get().JoinedMtIdx])
-    if err != nil {
-        err = errors.Wrap(err, "Failed to parse FirstMT from sheet")
-        return
+
+    // Fields whose column couldn't be found are left as zero
+    if cols.joinedMt >= 0 {
+        u.FirstMT, err = rc.str(cols.joinedMt, "FirstMT")
+        if err != nil {
+            return
+        }
+        u.FirstMT = strings.TrimPrefix(u.FirstMT, ".")
     }
-    if u.FirstMT[0] == '.' {
-        u.FirstMT = u.FirstMT[1:]
+    if cols.torneyCount >= 0 {
+        u.TourneyCount, err = rc.integer(cols.torneyCount, "TourneyCount")
+        if err != nil {
+            return
+        }
     }
-    u.TourneyCount, err = cellToInt(row[config.Get().TorneyCountIdx])
-    if err != nil {
-        err = errors.Wrap(err, "Failed to parse TourneyCount from sheet")
-        return
+    if cols.win >= 0 {
+        u.WinCount, err = rc.integer(cols.win, "WinCount")
+        if err != nil {
+            return
+        }
     }
-    u.WinCount, err = cellToInt(row[config.Get().WinIdx])
-    if err != nil {
-        err = errors.Wrap(err, "Failed to parse WinCount from sheet")
-        return
+    if cols.lose >= 0 {
+        u.LoseCount, err = rc.integer(cols.lose, "LoseCount")
+        if err != nil {
+            return
+        }
     }
-    u.LoseCount, err = cellToInt(row[config.Get().LoseIdx])
-    if err != nil {
-        err = errors.Wrap(err, "Failed to parse LoseCount from sheet")
-        return
+    if cols.draft >= 0 {
+        u.DraftPoints, err = rc.float(cols.draft, "DraftPoints")
     }
-    u.DraftPoints, err = cellToFloat(row[config.Get().DraftIdx])
-    // XXX: if err == nil, errors.Wrap returns nil as well!
-    err = errors.Wrap(err, "Failed to parse DraftPoints from sheet")
     return
 }
 
@@ -129,20 +158,21 @@ func min(a,b int) int {
     return b
 }
 
-// setHighestPosition from a player's row in the spreadsheet
-func (u *User) setHighestPosition(row []interface{}) (err error) {
-    u.HighestPosition = 9999
+// setHighestPosition from a player's row in the spreadsheet, converting each
+// column into a placement through idxToPlace
+func (u *User) setHighestPosition(rc rowCells, idxToPlace []int) (err error) {
+    u.HighestPosition = NoPlacement
     found := false
 
-    for i, v := range row {
-        if i < 2 || i >= len(_idxToPlace) {
+    for i := range rc.row {
+        if i < 2 || i >= len(idxToPlace) {
             continue
         }
-        cell, gerr := cellToInt(v)
+        cell, gerr := rc.integer(i, "user's highest position")
         if gerr != nil {
-            return errors.Wrap(gerr, "Failed to parse user's highest position")
+            return gerr
         } else if cell > 0 {
-            u.HighestPosition = min(u.HighestPosition, _idxToPlace[i])
+            u.HighestPosition = min(u.HighestPosition, idxToPlace[i])
             found = true
         }
     }
@@ -162,16 +192,16 @@ func colToStr(c interface{}) string {
     return s
 }
 
-// getUserRow, doing a case insensitive look up
-func getUserRow(username string, nameIdx int, rows[][]interface{}) []interface{} {
+// getUserRow retrieves the index of the user's row, doing a case insensitive
+// look up. Returns -1 if the user isn't found.
+func getUserRow(username string, nameIdx int, rows[][]interface{}) int {
     name := strings.ToLower(username)
     // First assume that the list is sorted and try a binary search
     for l, r := 0, len(rows) - 1; l <= r; {
         m := (l + r) / 2
-        row := rows[m]
-        switch strings.Compare(name, strings.ToLower(colToStr(row[nameIdx]))) {
+        switch strings.Compare(name, strings.ToLower(colToStr(cellOf(rows[m], nameIdx)))) {
         case 0:
-            return row
+            return m
         case 1:
             l = m + 1
         case -1:
@@ -179,76 +209,119 @@ func getUserRow(username string, nameIdx int, rows[][]interface{}) []interface{}
         }
     }
     // If not found, look sequentially
-    for _, row := range rows {
-        if strings.ToLower(colToStr(row[nameIdx])) == name {
-            return row
+    for i, row := range rows {
+        if strings.ToLower(colToStr(cellOf(row, nameIdx))) == name {
+            return i
         }
     }
 
-    return nil
+    return -1
 }
 
-// GetUserInfo from the MT Career spreadsheet
-func (s *Sheet) GetUserInfo(username string) (u User, err error) {
-    // Download and cache the participants info and standings through every
-    // tournament
-    if _tourneyCache == nil {
-        _range := fmt.Sprintf(baseRange,
-            config.Get().UserInfo.SheetName,
-            config.Get().UserInfo.FirstColumn,
-            config.Get().UserInfo.FirstRow,
-            config.Get().UserInfo.LastColumn,
-            s.TotalEntrants)
-        resp, gerr := s.srv.Spreadsheets.Values.Get(s.id, _range).Do()
+// Invalidate the downloaded spreadsheet, so it's downloaded again on the next
+// look up
+func Invalidate() {
+    _cache.Lock()
+    defer _cache.Unlock()
+
+    _cache.tourney = nil
+    _cache.standings = nil
+    _cache.idxToPlace = nil
+    _cache.entrants = nil
+}
+
+// expire the downloaded spreadsheet, so it's downloaded again, if it's older
+// than config.CacheTTL. Must be called with the lock held.
+func (c *sheetCache) expire() {
+    ttl := config.Get().CacheExpiration()
+    if ttl != 0 && time.Since(c.updated) >= ttl {
+        c.tourney = nil
+        c.standings = nil
+        c.idxToPlace = nil
+        c.entrants = nil
+    }
+}
+
+// load the participants info and standings through every tournament,
+// downloading them if they aren't cached (or if they have expired). The lock
+// is held while downloading, so concurrent look ups share a single download.
+func (c *sheetCache) load(s *Sheet) (tourney, standings [][]interface{}, idxToPlace []int, cols userColumns, err error) {
+    c.Lock()
+    defer c.Unlock()
+
+    c.expire()
+
+    if c.tourney == nil {
+        columns, missing, gerr := s.loadColumns()
+        if gerr != nil {
+            err = gerr
+            return
+        } else if len(missing) > 0 {
+            log.Printf("%+v", missingHeadersError(missing))
+        }
+        values, gerr := s.getRange(config.Get().UserInfo, s.TotalEntrants)
         if gerr != nil {
             err = errors.Wrap(gerr, "Unable to retrieve tourney data from sheet")
             return
         }
 
-        _tourneyCache = resp.Values
+        c.columns = columns
+        c.tourney = values
+        c.updated = time.Now()
     }
-    if _standingsCache == nil {
-        _range := fmt.Sprintf(baseRange,
-            config.Get().StandingsInfo.SheetName,
-            config.Get().StandingsInfo.FirstColumn,
-            config.Get().StandingsInfo.FirstRow,
-            config.Get().StandingsInfo.LastColumn,
-            s.TotalEntrants)
-        resp, gerr := s.srv.Spreadsheets.Values.Get(s.id, _range).Do()
+    if c.standings == nil {
+        values, gerr := s.getRange(config.Get().StandingsInfo, s.TotalEntrants)
         if gerr != nil {
             err = errors.Wrap(gerr, "Unable to retrieve standings from sheet")
             return
         }
 
         // Convert an index in the standings cache to a tournament placement
-        _standingsCache = resp.Values
-        for i, row := range _standingsCache[0] {
-            st, ok := row.(string)
-            if !ok || len(st) < 2 || i < 2 {
-                continue
-            }
-            // Remove the position suffix (e.g., 1st, 2nd etc)
-            val, gerr := strconv.ParseInt(st[:len(st)-2], 10, 64)
-            if gerr != nil {
-                err = errors.Wrap(gerr, "Unable to map standing index in sheet to tournament placement")
-                return
-            } else if val == 0 {
-                continue
+        places := []int{0, 0}
+        if len(values) > 0 {
+            for i, row := range values[0] {
+                st, ok := row.(string)
+                if !ok || len(st) < 2 || i < 2 {
+                    continue
+                }
+                // Remove the position suffix (e.g., 1st, 2nd etc)
+                val, gerr := strconv.ParseInt(st[:len(st)-2], 10, 64)
+                if gerr != nil {
+                    err = errors.Wrap(gerr, "Unable to map standing index in sheet to tournament placement")
+                    return
+                } else if val == 0 {
+                    continue
+                }
+                places = append(places, int(val))
             }
-            _idxToPlace = append(_idxToPlace, int(val))
         }
+
+        c.standings = values
+        c.idxToPlace = places
+    }
+
+    return c.tourney, c.standings, c.idxToPlace, c.columns, nil
+}
+
+// GetUserInfo from the MT Career spreadsheet
+func (s *Sheet) GetUserInfo(username string) (u User, err error) {
+    // Download and cache the participants info and standings through every
+    // tournament
+    tourney, standings, idxToPlace, cols, err := _cache.load(s)
+    if err != nil {
+        return
     }
 
     // Retrieve the user info from the previously downloaded data
-    row := getUserRow(username, config.Get().NameIdx, _tourneyCache)
-    if row == nil {
-        err = errors.New(fmt.Sprintf("User not found: '%s'", username))
+    row := getUserRow(username, cols.name, tourney)
+    if row == -1 {
+        err = errors.Wrap(ErrUserNotFound, fmt.Sprintf("Failed to find '%s'", username))
         return
     }
-    u, err = rowToUser(row)
+    u, err = rowToUser(newRowCells(tourney, config.Get().UserInfo, row), cols)
     if err == nil {
-        posRow := getUserRow(username, 0, _standingsCache)
-        err = u.setHighestPosition(posRow)
+        posRow := getUserRow(username, 0, standings)
+        err = u.setHighestPosition(newRowCells(standings, config.Get().StandingsInfo, posRow), idxToPlace)
         if errors.Cause(err) == errNoPlacing {
             err = nil
         }
@@ -256,3 +329,35 @@ func (s *Sheet) GetUserInfo(username string) (u User, err error) {
 
     return
 }
+
+// GetAllUsers from the MT Career spreadsheet, in the same order as in the
+// spreadsheet. Rows that fail to be parsed are skipped.
+func (s *Sheet) GetAllUsers() (users []User, err error) {
+    tourney, standings, idxToPlace, cols, err := _cache.load(s)
+    if err != nil {
+        return
+    }
+
+    for i := range tourney {
+        rc := newRowCells(tourney, config.Get().UserInfo, i)
+        if rc.blank(cols.name) {
+            // Not a user (e.g., a blank row at the end of the sheet)
+            continue
+        }
+        u, gerr := rowToUser(rc, cols)
+        if gerr != nil {
+            log.Printf("Skipping row %d of the MT Career spreadsheet: %+v", i, gerr)
+            continue
+        }
+
+        posRow := getUserRow(u.Username, 0, standings)
+        gerr = u.setHighestPosition(newRowCells(standings, config.Get().StandingsInfo, posRow), idxToPlace)
+        if gerr != nil && errors.Cause(gerr) != errNoPlacing {
+            log.Printf("Skipping row %d of the MT Career spreadsheet: %+v", i, gerr)
+            continue
+        }
+        users = append(users, u)
+    }
+
+    return
+}
@@ -0,0 +1,176 @@
+package mtcareers
+
+import (
+    "context"
+    "encoding/json"
+    "github.com/SirGFM/MTTitleCard/config"
+    "google.golang.org/api/option"
+    "google.golang.org/api/sheets/v4"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+)
+
+// newTestSheet starts a fake spreadsheet server, returning a Sheet accessing
+// it and the number of ranges downloaded from it
+func newTestSheet(t *testing.T) (*Sheet, *int32) {
+    var count int32
+
+    ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+        atomic.AddInt32(&count, 1)
+
+        var values [][]interface{}
+        switch {
+        case strings.Contains(req.URL.Path, config.Get().StandingsInfo.SheetName):
+            values = [][]interface{} {
+                {"Name", "MTs", "1st", "2nd", "3rd", "4th"},
+                {"Alice", "3", "0", "1", "2", "0"},
+                {"Bob", "1", "0", "0", "0", "0"},
+            }
+        case strings.Contains(req.URL.Path, config.Get().MtEntrantsInfo.SheetName):
+            values = [][]interface{} {
+                {"MT1", "MT9", "MT10"},
+                {"5", "3", ""},
+            }
+        case strings.Contains(req.URL.Path, config.Get().UserInfo.SheetName + "!A1:"):
+            values = [][]interface{} {
+                {"First MT", "Name", "MTs", "Wins", "Losses", "", "", "Draft Points"},
+            }
+        case strings.Contains(req.URL.Path, config.Get().UserInfo.SheetName):
+            values = [][]interface{} {
+                {".MT1", "Alice", "3", "10", "4", "", "", "12.5"},
+                {"MT9", "Bob", "1", "0", "2", "", "", "0"},
+            }
+        default:
+            http.Error(w, "Unknown range", http.StatusNotFound)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(sheets.ValueRange {
+            Values: values,
+        })
+    }))
+    t.Cleanup(ts.Close)
+
+    srv, err := sheets.NewService(context.Background(),
+        option.WithHTTPClient(ts.Client()),
+        option.WithEndpoint(ts.URL + "/"))
+    if err != nil {
+        t.Fatalf("Failed to create the sheets service: %+v", err)
+    }
+
+    return &Sheet {
+        reader: &googleSheet {
+            srv: srv,
+            id: "test",
+        },
+        TotalEntrants: 3,
+        LatestEntrants: 2,
+    }, &count
+}
+
+func TestConcurrentGetUserInfo(t *testing.T) {
+    err := config.LoadConfig(config.GetDefault())
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+    Invalidate()
+    s, count := newTestSheet(t)
+
+    var wg sync.WaitGroup
+    for i := 0; i < 16; i++ {
+        wg.Add(1)
+        go func(name string) {
+            defer wg.Done()
+            u, err := s.GetUserInfo(name)
+            if err != nil {
+                t.Errorf("Failed to get '%s': %+v", name, err)
+            } else if !strings.EqualFold(u.Username, name) {
+                t.Errorf("Got the wrong user: %+v", u)
+            }
+        } ([]string{"alice", "Bob"}[i % 2])
+    }
+    wg.Wait()
+
+    if n := atomic.LoadInt32(count); n != 3 {
+        t.Fatalf("Expected each range to be downloaded once, got %d downloads", n)
+    }
+
+    u, err := s.GetUserInfo("Alice")
+    if err != nil {
+        t.Fatalf("Failed to get user: %+v", err)
+    } else if u.FirstMT != "MT1" || u.WinCount != 10 || u.DraftPoints != 12.5 {
+        t.Fatalf("Failed to parse user: %+v", u)
+    } else if u.HighestPosition != 2 {
+        t.Fatalf("Expected highest position 2, got %d", u.HighestPosition)
+    }
+
+    _cache.Lock()
+    places := _cache.idxToPlace
+    _cache.Unlock()
+    if len(places) != 6 {
+        t.Fatalf("Standings were mapped more than once: %+v", places)
+    }
+}
+
+func TestGetAllUsers(t *testing.T) {
+    err := config.LoadConfig(config.GetDefault())
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+    Invalidate()
+    s, _ := newTestSheet(t)
+
+    users, err := s.GetAllUsers()
+    if err != nil {
+        t.Fatalf("Failed to get every user: %+v", err)
+    } else if len(users) != 2 {
+        t.Fatalf("Expected 2 users, got %+v", users)
+    }
+    if users[0].Username != "Alice" || users[0].HighestPosition != 2 {
+        t.Errorf("Failed to parse Alice: %+v", users[0])
+    }
+    if users[1].Username != "Bob" || users[1].HighestPosition != NoPlacement {
+        t.Errorf("Expected Bob to have never placed: %+v", users[1])
+    }
+}
+
+func TestGetTournament(t *testing.T) {
+    err := config.LoadConfig(config.GetDefault())
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+    Invalidate()
+    s, _ := newTestSheet(t)
+
+    tour, err := s.GetTournament()
+    if err != nil {
+        t.Fatalf("Failed to get the tournament: %+v", err)
+    }
+    if len(tour.MTs) != 2 || tour.MTs[0].Name != "MT1" || tour.MTs[0].Entrants != 5 || tour.MTs[0].NewPlayers != 1 {
+        t.Errorf("Failed to parse the entrants of each MT: %+v", tour.MTs)
+    }
+    if tour.UniquePlayers != 2 {
+        t.Errorf("Expected 2 unique players, got %d", tour.UniquePlayers)
+    }
+    if tour.LatestEntrants != 2 || tour.NewPlayers != 1 || tour.ReturningPlayers != 1 {
+        t.Errorf("Expected 1 new and 1 returning player: %+v", tour)
+    }
+    if len(tour.Champions) != 0 {
+        t.Errorf("Expected no champion: %+v", tour.Champions)
+    }
+
+    champions := getChampions([][]interface{} {
+        {"Name", "MTs", "1st", "2nd"},
+        {"Alice", "3", "1", "1"},
+        {"Bob", "5", "2", ""},
+        {"Carol", "1", "", "1"},
+    }, []int{0, 0, 1, 2})
+    if len(champions) != 2 || champions[0].Username != "Bob" || champions[0].Titles != 2 || champions[1].Username != "Alice" {
+        t.Errorf("Failed to list the champions: %+v", champions)
+    }
+}
@@ -0,0 +1,283 @@
+package mtcareers
+
+import (
+    "encoding/csv"
+    "github.com/pkg/errors"
+    mttcConfig "github.com/SirGFM/MTTitleCard/config"
+    "log"
+    "math"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+)
+
+// localFiles reads ranges from local copies of the spreadsheet: either from a
+// XLSX (or ODS) copy of the whole workbook, or from CSV (or TSV) exports of
+// each sheet
+type localFiles struct {
+    // workbook is a copy of the whole spreadsheet. If set, files is ignored.
+    workbook string
+    // files maps each sheet's name to its export
+    files map[string]string
+}
+
+// localModTimes stores when each local file was last modified, so the cached
+// data is discarded whenever they change
+type localModTimes struct {
+    sync.Mutex
+    // times maps each file to when it was last modified
+    times map[string]time.Time
+    // changed is set whenever the files change, until LocalFilesChanged
+    // reports it
+    changed bool
+}
+
+// _localModTimes stores when each local file was last modified
+var _localModTimes localModTimes
+
+// UsesLocalFiles checks whether the data is read from local copies of the
+// spreadsheet, instead of from Google Sheets
+func UsesLocalFiles() bool {
+    cfg := mttcConfig.Get()
+    return cfg.WorkbookFile != "" || len(cfg.CsvFiles) != 0
+}
+
+// GetLocalSheet retrieves an object for accessing the local copies of the
+// spreadsheet. If any file was modified since it was last accessed, the cached
+// data is discarded, so it's read again.
+func GetLocalSheet() (*Sheet, error) {
+    l := newLocalFiles()
+    _localModTimes.check(l.paths())
+
+    return &Sheet {
+        reader: l,
+    }, nil
+}
+
+// LocalFilesChanged checks whether any of the local copies of the spreadsheet
+// was modified since this was last called. The cached data is discarded as
+// soon as the modification is detected, either here or when the data is read.
+func LocalFilesChanged() bool {
+    if !UsesLocalFiles() {
+        return false
+    }
+    return _localModTimes.report(newLocalFiles().paths())
+}
+
+// newLocalFiles retrieves the local copies of the spreadsheet, as configured
+func newLocalFiles() *localFiles {
+    cfg := mttcConfig.Get()
+    l := &localFiles {
+        workbook: cfg.WorkbookFile,
+    }
+    if l.workbook == "" {
+        l.files = cfg.CsvFiles
+    }
+    return l
+}
+
+// paths lists every file read
+func (l *localFiles) paths() []string {
+    if l.workbook != "" {
+        return []string{l.workbook}
+    }
+
+    var list []string
+    for _, path := range l.files {
+        list = append(list, path)
+    }
+    return list
+}
+
+// check whether any of the files was modified and, if so, invalidate the
+// cached data
+func (m *localModTimes) check(paths []string) {
+    times := map[string]time.Time{}
+    for _, path := range paths {
+        if st, err := os.Stat(path); err == nil {
+            times[path] = st.ModTime()
+        }
+    }
+
+    m.Lock()
+    defer m.Unlock()
+
+    changed := len(times) != len(m.times)
+    for path, t := range times {
+        if last, ok := m.times[path]; !ok || !last.Equal(t) {
+            changed = true
+        }
+    }
+    if changed {
+        if m.times != nil {
+            log.Print("The local MT Career files changed, reloading them...")
+            m.changed = true
+        }
+        m.times = times
+        Invalidate()
+    }
+}
+
+// report whether any of the files was modified since the last report,
+// checking them once again
+func (m *localModTimes) report(paths []string) bool {
+    m.check(paths)
+
+    m.Lock()
+    defer m.Unlock()
+
+    changed := m.changed
+    m.changed = false
+    return changed
+}
+
+// Limits of a sheet, as in XLSX files
+const (
+    maxRows = 1048576
+    maxColumns = 16384
+)
+
+// columnIndex converts a column (e.g., "A", "F" or "AB") to its index,
+// starting at 0
+func columnIndex(col string) (int, error) {
+    if col == "" {
+        return 0, errors.New("Empty column")
+    } else if len(col) > 3 {
+        return 0, errors.Errorf("Invalid column '%s'", col)
+    }
+
+    idx := 0
+    for _, c := range strings.ToUpper(col) {
+        if c < 'A' || c > 'Z' {
+            return 0, errors.Errorf("Invalid column '%s'", col)
+        }
+        idx = idx * 26 + int(c - 'A') + 1
+    }
+    if idx > maxColumns {
+        return 0, errors.Errorf("Invalid column '%s'", col)
+    }
+    return idx - 1, nil
+}
+
+// columnName converts the index of a column, starting at 0, to its name
+// (e.g., "A", "F" or "AB")
+func columnName(idx int) string {
+    var name []byte
+    for idx++; idx > 0; idx = (idx - 1) / 26 {
+        name = append([]byte{byte('A' + (idx - 1) % 26)}, name...)
+    }
+    return string(name)
+}
+
+// formatNumber formats a number stored in a workbook as Google's API would,
+// so integers are parsed as such (e.g., "3" instead of "3.0000000000000004").
+// The cell's number format isn't applied, so, unlike Google's API, dates are
+// kept as serial numbers and percentages as fractions.
+func formatNumber(val string) string {
+    f, err := strconv.ParseFloat(val, 64)
+    if err != nil {
+        return val
+    }
+    if r := math.Round(f); math.Abs(f - r) < 1e-9 && math.Abs(r) < 1e15 {
+        return strconv.FormatInt(int64(r), 10)
+    }
+    return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// formatBool formats a boolean stored in a workbook as Google's API would
+func formatBool(b bool) string {
+    if b {
+        return "TRUE"
+    }
+    return "FALSE"
+}
+
+// readCsv reads every record in a CSV file. Files with a ".tsv" extension are
+// read as tab-separated.
+func readCsv(path string) ([][]string, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to open the local file")
+    }
+    defer f.Close()
+
+    r := csv.NewReader(f)
+    if strings.EqualFold(filepath.Ext(path), ".tsv") {
+        r.Comma = '\t'
+    }
+    r.FieldsPerRecord = -1
+    r.LazyQuotes = true
+
+    records, err := r.ReadAll()
+    if err != nil {
+        return nil, errors.Wrapf(err, "Failed to parse '%s'", path)
+    }
+    // Spreadsheet applications may start the file with a byte order mark
+    if len(records) > 0 && len(records[0]) > 0 {
+        records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+    }
+    return records, nil
+}
+
+// readSheet reads every cell in a sheet, either from the workbook or from the
+// sheet's export
+func (l *localFiles) readSheet(sheetName string) ([][]string, error) {
+    if l.workbook != "" {
+        switch strings.ToLower(filepath.Ext(l.workbook)) {
+        case ".xlsx":
+            return readXlsx(l.workbook, sheetName)
+        case ".ods":
+            return readOds(l.workbook, sheetName)
+        default:
+            return nil, errors.Errorf("Unsupported workbook '%s': it must be a .xlsx or .ods file", l.workbook)
+        }
+    }
+
+    path, ok := l.files[sheetName]
+    if !ok {
+        return nil, errors.Errorf("No local file configured for the sheet '%s'", sheetName)
+    }
+    return readCsv(path)
+}
+
+// readRange reads a range from the local copy of its sheet. Just like Google's
+// API, trailing empty cells and trailing empty rows are omitted.
+func (l *localFiles) readRange(r mttcConfig.SheetRange, lastRow int) ([][]interface{}, error) {
+    first, err := columnIndex(r.FirstColumn)
+    if err != nil {
+        return nil, errors.Wrapf(err, "Invalid range in the sheet '%s'", r.SheetName)
+    }
+    last, err := columnIndex(r.LastColumn)
+    if err != nil {
+        return nil, errors.Wrapf(err, "Invalid range in the sheet '%s'", r.SheetName)
+    }
+
+    records, err := l.readSheet(r.SheetName)
+    if err != nil {
+        return nil, err
+    }
+
+    var values [][]interface{}
+    for i := r.FirstRow - 1; i < lastRow && i < len(records); i++ {
+        if i < 0 {
+            continue
+        }
+
+        row := []interface{}{}
+        for j := first; j <= last && j < len(records[i]); j++ {
+            row = append(row, records[i][j])
+        }
+        for len(row) > 0 && row[len(row) - 1] == "" {
+            row = row[:len(row) - 1]
+        }
+        values = append(values, row)
+    }
+    for len(values) > 0 && len(values[len(values) - 1]) == 0 {
+        values = values[:len(values) - 1]
+    }
+
+    return values, nil
+}
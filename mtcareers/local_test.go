@@ -0,0 +1,184 @@
+package mtcareers
+
+import (
+    "github.com/SirGFM/MTTitleCard/config"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+// writeLocal writes a local file, setting its modification time to mod
+func writeLocal(t *testing.T, path, data string, mod time.Time) {
+    err := ioutil.WriteFile(path, []byte(data), 0600)
+    if err != nil {
+        t.Fatalf("Failed to write '%s': %+v", path, err)
+    }
+    err = os.Chtimes(path, mod, mod)
+    if err != nil {
+        t.Fatalf("Failed to set the modification time of '%s': %+v", path, err)
+    }
+}
+
+// setupLocal exports a small spreadsheet to CSV and TSV files, configuring
+// the server to read them. Returns the path to the career file.
+func setupLocal(t *testing.T) string {
+    dir := t.TempDir()
+    mod := time.Now().Add(-time.Hour)
+
+    stats := filepath.Join(dir, "stats.csv")
+    writeLocal(t, stats, "MT1,MT9,MT10\n" +
+            "5,3,\n" +
+            "Total,,10,0,0,2\n", mod)
+    career := filepath.Join(dir, "career.csv")
+    writeLocal(t, career, "\ufeffFirst MT,Name,MTs,Wins,Losses,,,Draft Points\n" +
+            ".MT1,Alice,3,10,4,,,12.5\n" +
+            "MT9,\"Bob\",1,0,2,,,0\n", mod)
+    standings := filepath.Join(dir, "standings.tsv")
+    writeLocal(t, standings, "Name\tMTs\t1st\t2nd\t3rd\t4th\n" +
+            "Alice\t3\t0\t1\t2\t0\n" +
+            "Bob\t1\t0\t0\t0\t0\n", mod)
+
+    setupLocalConfig(t, func(cfg *config.Config) {
+        cfg.CsvFiles = map[string]string {
+            "Stats": stats,
+            "Career": career,
+            "Standings": standings,
+        }
+    })
+
+    return career
+}
+
+// setupLocalConfig configures the ranges of the test spreadsheet, so it may be
+// read from local files configured by setFiles
+func setupLocalConfig(t *testing.T, setFiles func(*config.Config)) {
+    prev := config.Get()
+    cfg := config.GetDefault()
+    cfg.TourneyInfo = config.SheetRange{SheetName: "Stats", FirstColumn: "C", LastColumn: "F", FirstRow: 3}
+    cfg.MtEntrantsInfo = config.SheetRange{SheetName: "Stats", FirstColumn: "A", LastColumn: "C", FirstRow: 1}
+    cfg.UserInfo = config.SheetRange{SheetName: "Career", FirstColumn: "A", LastColumn: "H", FirstRow: 2}
+    cfg.StandingsInfo = config.SheetRange{SheetName: "Standings", FirstColumn: "A", LastColumn: "F", FirstRow: 1}
+    setFiles(&cfg)
+    config.LoadConfig(cfg)
+    resetModTimes := func() {
+        _localModTimes.Lock()
+        _localModTimes.times = nil
+        _localModTimes.changed = false
+        _localModTimes.Unlock()
+    }
+    resetModTimes()
+    t.Cleanup(func() {
+        config.LoadConfig(prev)
+        resetModTimes()
+        Invalidate()
+    })
+}
+
+// checkLocalData verifies that the test spreadsheet was read from the local
+// files
+func checkLocalData(t *testing.T) {
+    if !UsesLocalFiles() || ValidToken() != nil {
+        t.Fatalf("Expected local files not to need any token")
+    }
+
+    sh, err := GetProvider()
+    if err != nil {
+        t.Fatalf("Failed to get the provider: %+v", err)
+    }
+    err = sh.GetTourneyInfo()
+    if err != nil {
+        t.Fatalf("Failed to get the tourney info: %+v", err)
+    }
+
+    u, err := sh.GetUserInfo("alice")
+    if err != nil {
+        t.Fatalf("Failed to get the user: %+v", err)
+    }
+    if u.Username != "Alice" || u.FirstMT != "MT1" || u.WinCount != 10 || u.DraftPoints != 12.5 || u.HighestPosition != 2 {
+        t.Errorf("Unexpected user: %+v", u)
+    }
+    users, err := sh.GetAllUsers()
+    if err != nil || len(users) != 2 || users[1].Username != "Bob" || users[1].HighestPosition != NoPlacement {
+        t.Errorf("Unexpected users: %+v (%+v)", users, err)
+    }
+    tour, err := sh.GetTournament()
+    if err != nil || tour.TotalEntrants != 10 || tour.LatestEntrants != 2 || len(tour.MTs) != 2 {
+        t.Errorf("Unexpected tournament: %+v (%+v)", tour, err)
+    }
+}
+
+func TestLocalFiles(t *testing.T) {
+    career := setupLocal(t)
+    checkLocalData(t)
+
+    // Modify the file, which must be read again on the next access
+    writeLocal(t, career, "First MT,Name,MTs,Wins,Losses,,,Draft Points\n" +
+            "MT1,Alice,4,11,4,,,12.5\n", time.Now())
+
+    sh, err := GetProvider()
+    if err != nil {
+        t.Fatalf("Failed to get the provider: %+v", err)
+    }
+    sh.GetTourneyInfo()
+    u, err := sh.GetUserInfo("Alice")
+    if err != nil || u.WinCount != 11 {
+        t.Errorf("Expected the modified file to be reloaded, got %+v (%+v)", u, err)
+    }
+}
+
+func TestLocalFilesChanged(t *testing.T) {
+    career := setupLocal(t)
+    if LocalFilesChanged() {
+        t.Errorf("Expected the files to be unchanged on the first check")
+    }
+    checkLocalData(t)
+
+    writeLocal(t, career, "First MT,Name,MTs,Wins,Losses,,,Draft Points\n" +
+            "MT1,Alice,4,11,4,,,12.5\n", time.Now())
+    if !LocalFilesChanged() {
+        t.Errorf("Expected the modification to be reported")
+    }
+    if LocalFilesChanged() {
+        t.Errorf("Expected the modification to be reported only once")
+    }
+
+    // The modification is still reported if it was detected while reading
+    writeLocal(t, career, "First MT,Name,MTs,Wins,Losses,,,Draft Points\n" +
+            "MT1,Alice,5,12,4,,,12.5\n", time.Now().Add(time.Minute))
+    GetProvider()
+    if !LocalFilesChanged() {
+        t.Errorf("Expected the modification detected while reading to be reported")
+    }
+}
+
+func TestColumnIndex(t *testing.T) {
+    for _, tc := range []struct {
+        col string
+        idx int
+    } {
+        {"A", 0},
+        {"h", 7},
+        {"Z", 25},
+        {"AA", 26},
+        {"AZ", 51},
+        {"BA", 52},
+        {"XFD", maxColumns - 1},
+    } {
+        idx, err := columnIndex(tc.col)
+        if err != nil || idx != tc.idx {
+            t.Errorf("Expected '%s' to be %d, got %d (%+v)", tc.col, tc.idx, idx, err)
+        }
+        if name := columnName(tc.idx); name != strings.ToUpper(tc.col) {
+            t.Errorf("Expected %d to be '%s', got '%s'", tc.idx, strings.ToUpper(tc.col), name)
+        }
+    }
+
+    for _, col := range []string{"", "A1", "-"} {
+        if _, err := columnIndex(col); err == nil {
+            t.Errorf("Expected '%s' to be rejected", col)
+        }
+    }
+}
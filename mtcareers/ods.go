@@ -0,0 +1,256 @@
+package mtcareers
+
+import (
+    "archive/zip"
+    "encoding/xml"
+    "github.com/pkg/errors"
+    "io"
+    "strconv"
+    "strings"
+)
+
+// Namespaces of the elements read from ODS files
+const (
+    odsOffice = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+    odsTable = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+    odsText = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+// odsAttr retrieves an attribute from an element, or "" if it's missing
+func odsAttr(el xml.StartElement, space, local string) string {
+    for _, a := range el.Attr {
+        if a.Name.Space == space && a.Name.Local == local {
+            return a.Value
+        }
+    }
+    return ""
+}
+
+// odsRepeat retrieves how many times an element is repeated, limited to max
+func odsRepeat(el xml.StartElement, local string, max int) int {
+    n, err := strconv.Atoi(odsAttr(el, odsTable, local))
+    if err != nil || n < 1 {
+        return 1
+    } else if n > max {
+        return max
+    }
+    return n
+}
+
+// readOdsText reads the text within a paragraph (or a span within it),
+// consuming every token up to its end
+func readOdsText(d *xml.Decoder, b *strings.Builder) error {
+    for {
+        tok, err := d.Token()
+        if err != nil {
+            return err
+        }
+
+        switch t := tok.(type) {
+        case xml.CharData:
+            b.Write(t)
+        case xml.StartElement:
+            switch {
+            case t.Name.Space == odsText && t.Name.Local == "s":
+                n, err := strconv.Atoi(odsAttr(t, odsText, "c"))
+                if err != nil || n < 1 {
+                    n = 1
+                }
+                b.WriteString(strings.Repeat(" ", n))
+            case t.Name.Space == odsText && t.Name.Local == "tab":
+                b.WriteString("\t")
+            case t.Name.Space == odsText && t.Name.Local == "line-break":
+                b.WriteString("\n")
+            case t.Name.Space == odsOffice && t.Name.Local == "annotation":
+                // Comments aren't part of the cell's text
+                err = d.Skip()
+                if err != nil {
+                    return err
+                }
+                continue
+            default:
+                err = readOdsText(d, b)
+                if err != nil {
+                    return err
+                }
+                continue
+            }
+            err = d.Skip()
+            if err != nil {
+                return err
+            }
+        case xml.EndElement:
+            return nil
+        }
+    }
+}
+
+// readOdsCell reads the value of a cell, consuming every token up to its end.
+// Numbers and booleans are retrieved from their value, instead of from their
+// (formatted) text.
+func readOdsCell(d *xml.Decoder, el xml.StartElement) (string, error) {
+    var b strings.Builder
+    paragraphs := 0
+
+    for {
+        tok, err := d.Token()
+        if err != nil {
+            return "", err
+        }
+
+        switch t := tok.(type) {
+        case xml.StartElement:
+            if t.Name.Space == odsText && t.Name.Local == "p" {
+                if paragraphs > 0 {
+                    b.WriteString("\n")
+                }
+                paragraphs++
+                err = readOdsText(d, &b)
+            } else {
+                err = d.Skip()
+            }
+            if err != nil {
+                return "", err
+            }
+        case xml.EndElement:
+            switch odsAttr(el, odsOffice, "value-type") {
+            case "float", "percentage", "currency":
+                return formatNumber(odsAttr(el, odsOffice, "value")), nil
+            case "boolean":
+                return formatBool(odsAttr(el, odsOffice, "boolean-value") == "true"), nil
+            }
+            return b.String(), nil
+        }
+    }
+}
+
+// readOdsRow reads every cell in a row, consuming every token up to its end.
+// Trailing empty cells are omitted, since they may be repeated up to the end of
+// the sheet.
+func readOdsRow(d *xml.Decoder) ([]string, error) {
+    var cells []string
+    empty := 0
+
+    for {
+        tok, err := d.Token()
+        if err != nil {
+            return nil, err
+        }
+
+        switch t := tok.(type) {
+        case xml.StartElement:
+            if t.Name.Space != odsTable || (t.Name.Local != "table-cell" && t.Name.Local != "covered-table-cell") {
+                err = d.Skip()
+                if err != nil {
+                    return nil, err
+                }
+                continue
+            }
+
+            repeat := odsRepeat(t, "number-columns-repeated", maxColumns)
+            val, err := readOdsCell(d, t)
+            if err != nil {
+                return nil, err
+            }
+
+            if val == "" {
+                empty += repeat
+                continue
+            }
+            for ; empty > 0 && len(cells) < maxColumns; empty-- {
+                cells = append(cells, "")
+            }
+            empty = 0
+            for i := 0; i < repeat && len(cells) < maxColumns; i++ {
+                cells = append(cells, val)
+            }
+        case xml.EndElement:
+            return cells, nil
+        }
+    }
+}
+
+// readOds reads every cell in a sheet of an ODS workbook
+func readOds(file, sheetName string) ([][]string, error) {
+    zr, err := zip.OpenReader(file)
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to open the workbook")
+    }
+    defer zr.Close()
+
+    var content io.ReadCloser
+    for _, f := range zr.File {
+        if f.Name == "content.xml" {
+            content, err = f.Open()
+            if err != nil {
+                return nil, errors.Wrap(err, "Failed to open 'content.xml'")
+            }
+            defer content.Close()
+        }
+    }
+    if content == nil {
+        return nil, errors.New("Missing 'content.xml'")
+    }
+
+    d := xml.NewDecoder(content)
+    var records [][]string
+    empty := 0
+    found := false
+
+    for {
+        tok, err := d.Token()
+        if err == io.EOF {
+            break
+        } else if err != nil {
+            return nil, errors.Wrap(err, "Failed to parse 'content.xml'")
+        }
+
+        switch t := tok.(type) {
+        case xml.StartElement:
+            if t.Name.Space != odsTable {
+                continue
+            }
+
+            switch t.Name.Local {
+            case "table":
+                if found || odsAttr(t, odsTable, "name") != sheetName {
+                    err = d.Skip()
+                } else {
+                    found = true
+                }
+            case "table-row":
+                if !found {
+                    continue
+                }
+                repeat := odsRepeat(t, "number-rows-repeated", maxRows)
+                row, err := readOdsRow(d)
+                if err != nil {
+                    return nil, errors.Wrap(err, "Failed to parse 'content.xml'")
+                }
+
+                // Empty rows may be repeated up to the end of the sheet, so
+                // they are only added if followed by another row
+                if len(row) == 0 {
+                    empty += repeat
+                    continue
+                }
+                for ; empty > 0 && len(records) < maxRows; empty-- {
+                    records = append(records, nil)
+                }
+                empty = 0
+                for i := 0; i < repeat && len(records) < maxRows; i++ {
+                    records = append(records, row)
+                }
+            }
+            if err != nil {
+                return nil, errors.Wrap(err, "Failed to parse 'content.xml'")
+            }
+        case xml.EndElement:
+            if found && t.Name.Space == odsTable && t.Name.Local == "table" {
+                return records, nil
+            }
+        }
+    }
+
+    return nil, errors.Errorf("Sheet '%s' not found in '%s'", sheetName, file)
+}
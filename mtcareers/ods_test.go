@@ -0,0 +1,104 @@
+package mtcareers
+
+import (
+    "github.com/SirGFM/MTTitleCard/config"
+    "path/filepath"
+    "testing"
+)
+
+// odsContent wraps the tables of an ODS document
+func odsContent(tables string) string {
+    return `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:calcext="urn:org:documentfoundation:names:experimental:calc:xmlns:calcext:1.0" office:version="1.2">
+<office:body><office:spreadsheet>` + tables + `</office:spreadsheet></office:body>
+</office:document-content>`
+}
+
+// odsString is a cell with a text
+func odsString(text string) string {
+    return `<table:table-cell office:value-type="string" calcext:value-type="string"><text:p>` + text + `</text:p></table:table-cell>`
+}
+
+// odsFloat is a cell with a number, formatted as text
+func odsFloat(value, text string) string {
+    return `<table:table-cell office:value-type="float" office:value="` + value + `" calcext:value-type="float"><text:p>` + text + `</text:p></table:table-cell>`
+}
+
+func TestOdsWorkbook(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "careers.ods")
+    writeZip(t, path, map[string]string {
+        "mimetype": "application/vnd.oasis.opendocument.spreadsheet",
+        "content.xml": odsContent(`
+<table:table table:name="Other"><table:table-row>` + odsString("Ignored") + `</table:table-row></table:table>
+<table:table table:name="Stats">
+<table:table-column table:number-columns-repeated="6"/>
+<table:table-row>` + odsString("MT1") + odsString("MT9") + odsString("MT10") + `<table:table-cell table:number-columns-repeated="1021"/></table:table-row>
+<table:table-row>` + odsFloat("5", "5") + odsFloat("3", "3") + `</table:table-row>
+<table:table-row>` + odsString("Total") + `<table:table-cell/>` + odsFloat("10", "10") +
+        `<table:table-cell office:value-type="boolean" office:boolean-value="true"><text:p>TRUE</text:p></table:table-cell>` +
+        `<table:table-cell/>` + odsFloat("2", "2") + `</table:table-row>
+<table:table-row table:number-rows-repeated="1048573"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table>
+<table:table table:name="Career">
+<table:table-header-rows><table:table-row>` + odsString("First MT") + odsString("Name") + odsString("MTs") + odsString("Wins") + odsString("Losses") +
+        `<table:table-cell table:number-columns-repeated="2"/>` + odsString("Draft Points") + `</table:table-row></table:table-header-rows>
+<table:table-row>` + odsString(".MT1") + `<table:table-cell office:value-type="string"><text:p>Al<text:span>ice</text:span></text:p>` +
+        `<office:annotation><text:p>Comment</text:p></office:annotation></table:table-cell>` +
+        odsFloat("3", "3") + odsFloat("10", "10") + odsFloat("4", "4") + `<table:table-cell table:number-columns-repeated="2"/>` +
+        odsFloat("12.5", "12,50") + `</table:table-row>
+<table:table-row>` + odsString("MT9") + odsString("Bob") + odsFloat("1", "1") + odsFloat("0", "0") + odsFloat("2", "2") +
+        `<table:table-cell table:number-columns-repeated="2"/>` + odsFloat("0", "0") + `</table:table-row>
+</table:table>
+<table:table table:name="Standings">
+<table:table-row>` + odsString("Name") + odsString("MTs") + odsString("1st") + odsString("2nd") + odsString("3rd") + odsString("4th") + `</table:table-row>
+<table:table-row>` + odsString("Alice") + odsFloat("3", "3") + odsFloat("0", "0") + odsFloat("1", "1") + odsFloat("2", "2") + odsFloat("0", "0") + `</table:table-row>
+<table:table-row>` + odsString("Bob") + odsFloat("1", "1") + `<table:table-cell office:value-type="float" office:value="0" table:number-columns-repeated="4"><text:p>0</text:p></table:table-cell></table:table-row>
+</table:table>`),
+    })
+
+    setupLocalConfig(t, func(cfg *config.Config) {
+        cfg.WorkbookFile = path
+    })
+    checkLocalData(t)
+
+    records, err := readOds(path, "Stats")
+    if err != nil || len(records) != 3 || records[2][3] != "TRUE" || len(records[0]) != 3 {
+        t.Errorf("Unexpected cells: %+v (%+v)", records, err)
+    }
+    records, err = readOds(path, "Career")
+    if err != nil || len(records) != 3 || records[1][1] != "Alice" || records[2][7] != "0" {
+        t.Errorf("Unexpected cells: %+v (%+v)", records, err)
+    }
+    if _, err := readOds(path, "Missing"); err == nil {
+        t.Errorf("Expected a missing sheet to be reported")
+    }
+}
+
+func TestOdsSpaces(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "spaces.ods")
+    writeZip(t, path, map[string]string {
+        "content.xml": odsContent(`<table:table table:name="Sheet1"><table:table-row>` +
+                `<table:table-cell><text:p>a<text:s text:c="2"/>b</text:p><text:p>c</text:p></table:table-cell>` +
+                `</table:table-row></table:table>`),
+    })
+
+    records, err := readOds(path, "Sheet1")
+    if err != nil || len(records) != 1 || records[0][0] != "a  b\nc" {
+        t.Errorf("Unexpected cells: %q (%+v)", records, err)
+    }
+}
+
+func TestOdsInvalidRow(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "careers.ods")
+    writeZip(t, path, map[string]string {
+        "mimetype": "application/vnd.oasis.opendocument.spreadsheet",
+        "content.xml": odsContent(`<table:table table:name="Stats">
+<table:table-row><table:table-cell><text:p>Broken</table:table-cell></table:table-row>
+</table:table>`),
+    })
+
+    _, err := readOds(path, "Stats")
+    if err == nil {
+        t.Errorf("Expected the invalid row to fail")
+    }
+}
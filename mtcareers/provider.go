@@ -0,0 +1,27 @@
+package mtcareers
+
+// Provider retrieves the MT Career data, regardless of where it's stored
+type Provider interface {
+    // GetTourneyInfo retrieve the total number of entrants and the number of
+    // entrants in the latest tournament. Must be called before retrieving
+    // anything else.
+    GetTourneyInfo() error
+    // GetUserInfo retrieves a user's career, or ErrUserNotFound
+    GetUserInfo(username string) (User, error)
+    // GetAllUsers retrieves the career of every user
+    GetAllUsers() ([]User, error)
+    // GetTournament retrieves the series-wide info
+    GetTournament() (Tournament, error)
+}
+
+// Sheet retrieves the data from the MT Career spreadsheet
+var _ Provider = (*Sheet)(nil)
+
+// GetProvider retrieves the configured source of MT Career data: either the
+// local exports of the spreadsheet, if configured, or Google Sheets
+func GetProvider() (Provider, error) {
+    if UsesLocalFiles() {
+        return GetLocalSheet()
+    }
+    return GetSheet()
+}
@@ -0,0 +1,105 @@
+package mtcareers
+
+import (
+    "fmt"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "strings"
+)
+
+// rowCells reads the cells in a row retrieved from the spreadsheet, reporting
+// where each cell that fails to be parsed is
+type rowCells struct {
+    row []interface{}
+    // r is the range from which the row was retrieved
+    r config.SheetRange
+    // idx is the row's index within the range
+    idx int
+    // lenient reads blank cells as zero (or as an empty text)
+    lenient bool
+}
+
+// newRowCells reads the idx-th row retrieved from a range, as configured by
+// LenientRows
+func newRowCells(rows [][]interface{}, r config.SheetRange, idx int) rowCells {
+    rc := rowCells {
+        r: r,
+        idx: idx,
+        lenient: config.Get().LenientRows,
+    }
+    if idx >= 0 && idx < len(rows) {
+        rc.row = rows[idx]
+    }
+    return rc
+}
+
+// cellOf retrieves a cell from a row, or nil if the row is too short
+func cellOf(row []interface{}, col int) interface{} {
+    if col < 0 || col >= len(row) {
+        return nil
+    }
+    return row[col]
+}
+
+// ref formats the location of a cell in the row (e.g., 'MT Career'!D12)
+func (rc rowCells) ref(col int) string {
+    first, err := columnIndex(rc.r.FirstColumn)
+    if err != nil {
+        return fmt.Sprintf("'%s'!R%dC%d", rc.r.SheetName, rc.r.FirstRow + rc.idx, col + 1)
+    }
+    return fmt.Sprintf("'%s'!%s%d", rc.r.SheetName, columnName(first + col), rc.r.FirstRow + rc.idx)
+}
+
+// blank checks whether a cell is either missing or empty
+func (rc rowCells) blank(col int) bool {
+    c := cellOf(rc.row, col)
+    if s, ok := c.(string); ok {
+        return strings.TrimSpace(s) == ""
+    }
+    return c == nil
+}
+
+// cell retrieves a cell from the row. Blank cells are retrieved as nil on
+// lenient rows, and fail otherwise.
+func (rc rowCells) cell(col int, field string) (interface{}, error) {
+    if rc.blank(col) {
+        if rc.lenient {
+            return nil, nil
+        }
+        return nil, errors.Errorf("Failed to parse %s from %s: Blank cell", field, rc.ref(col))
+    }
+    return rc.row[col], nil
+}
+
+// str retrieves a cell from the row as a string
+func (rc rowCells) str(col int, field string) (string, error) {
+    c, err := rc.cell(col, field)
+    if err != nil || c == nil {
+        return "", err
+    }
+    s, err := cellToStr(c)
+    // XXX: if err == nil, errors.Wrap returns nil as well!
+    return s, errors.Wrapf(err, "Failed to parse %s from %s", field, rc.ref(col))
+}
+
+// integer retrieves a cell from the row as an int
+func (rc rowCells) integer(col int, field string) (int, error) {
+    c, err := rc.cell(col, field)
+    if err != nil || c == nil {
+        return 0, err
+    }
+    i, err := cellToInt(c)
+    // XXX: if err == nil, errors.Wrap returns nil as well!
+    return i, errors.Wrapf(err, "Failed to parse %s from %s", field, rc.ref(col))
+}
+
+// float retrieves a cell from the row as a float
+func (rc rowCells) float(col int, field string) (float32, error) {
+    c, err := rc.cell(col, field)
+    if err != nil || c == nil {
+        return 0, err
+    }
+    f, err := cellToFloat(c)
+    // XXX: if err == nil, errors.Wrap returns nil as well!
+    return f, errors.Wrapf(err, "Failed to parse %s from %s", field, rc.ref(col))
+}
@@ -0,0 +1,126 @@
+package mtcareers
+
+import (
+    "github.com/SirGFM/MTTitleCard/config"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestRowToUser(t *testing.T) {
+    cfg := config.GetDefault()
+    cfg.UserInfo.FirstColumn = "B"
+    cfg.UserInfo.FirstRow = 3
+    err := config.LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+    t.Cleanup(func() {
+        config.LoadConfig(config.GetDefault())
+    })
+
+    cols := userColumns {
+        joinedMt: 0,
+        name: 1,
+        torneyCount: 2,
+        win: 3,
+        lose: 4,
+        draft: 7,
+    }
+    rows := [][]interface{} {
+        {".MT1", "Alice", "3", "10", "4", "", "", "12.5"},
+        // Google's API omits trailing blank cells
+        {"MT9", "Bob", "1", "0", "2"},
+        {"", "Carol", " ", "1", "x"},
+        {},
+    }
+
+    for _, tc := range []struct {
+        idx int
+        msg string
+    } {
+        {1, "Failed to parse DraftPoints from 'MT Career'!I4: Blank cell"},
+        {2, "Failed to parse FirstMT from 'MT Career'!B5: Blank cell"},
+        {3, "Failed to parse Username from 'MT Career'!C6: Blank cell"},
+        {4, "Failed to parse Username from 'MT Career'!C7: Blank cell"},
+    } {
+        _, err := rowToUser(newRowCells(rows, cfg.UserInfo, tc.idx), cols)
+        if err == nil || err.Error() != tc.msg {
+            t.Errorf("Expected row %d to fail with %q, got %v", tc.idx, tc.msg, err)
+        }
+    }
+
+    u, err := rowToUser(newRowCells(rows, cfg.UserInfo, 0), cols)
+    if err != nil || u.FirstMT != "MT1" || u.DraftPoints != 12.5 {
+        t.Errorf("Failed to parse the user: %+v (%+v)", u, err)
+    }
+
+    cfg.LenientRows = true
+    err = config.LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+
+    u, err = rowToUser(newRowCells(rows, cfg.UserInfo, 1), cols)
+    if err != nil || u.Username != "Bob" || u.LoseCount != 2 || u.DraftPoints != 0 {
+        t.Errorf("Expected the blank cell to be zeroed: %+v (%+v)", u, err)
+    }
+    _, err = rowToUser(newRowCells(rows, cfg.UserInfo, 2), cols)
+    if err == nil || !strings.HasPrefix(err.Error(), "Failed to parse LoseCount from 'MT Career'!F5: ") {
+        t.Errorf("Expected the invalid cell to be reported, got %v", err)
+    }
+    _, err = rowToUser(newRowCells(rows, cfg.UserInfo, 3), cols)
+    if err == nil {
+        t.Errorf("Expected users without a name to fail")
+    }
+}
+
+func TestShortRows(t *testing.T) {
+    setupLocalConfig(t, func(cfg *config.Config) {
+        cfg.CsvFiles = map[string]string {}
+    })
+
+    s := &Sheet {
+        reader: &localFiles {
+            files: map[string]string {},
+        },
+        TotalEntrants: 10,
+        LatestEntrants: 2,
+    }
+    _cache.Lock()
+    _cache.tourney = [][]interface{} {
+        {".MT1", "Alice", "3", "10", "4", "", "", "12.5"},
+        {"MT9", "Bob", "1"},
+        {},
+        {"", ""},
+    }
+    _cache.columns = userColumns{0, 1, 2, 3, 4, 7}
+    _cache.standings = [][]interface{} {
+        {"Name", "MTs", "1st", "2nd"},
+        {"Alice", "3", "", "1"},
+        {"Bob"},
+    }
+    _cache.idxToPlace = []int{0, 0, 1, 2}
+    _cache.updated = time.Now()
+    _cache.Unlock()
+
+    users, err := s.GetAllUsers()
+    if err != nil || len(users) != 0 {
+        t.Errorf("Expected every user to fail on blank cells: %+v (%+v)", users, err)
+    }
+    if _, err := s.GetUserInfo("Bob"); err == nil || !strings.Contains(err.Error(), "'Career'!D3") {
+        t.Errorf("Expected Bob's wins to be reported, got %v", err)
+    }
+
+    cfg := config.Get()
+    cfg.LenientRows = true
+    config.LoadConfig(cfg)
+
+    users, err = s.GetAllUsers()
+    if err != nil || len(users) != 2 {
+        t.Fatalf("Expected Alice and Bob: %+v (%+v)", users, err)
+    }
+    if users[0].HighestPosition != 2 || users[1].WinCount != 0 || users[1].HighestPosition != NoPlacement {
+        t.Errorf("Unexpected users: %+v", users)
+    }
+}
@@ -0,0 +1,39 @@
+package mtcareers
+
+import (
+    "github.com/pkg/errors"
+    mttcConfig "github.com/SirGFM/MTTitleCard/config"
+    "golang.org/x/net/context"
+    "golang.org/x/oauth2/google"
+    "golang.org/x/oauth2/jwt"
+    "io/ioutil"
+    "net/http"
+)
+
+// UsesServiceAccount checks whether the spreadsheet is accessed through a
+// service account, instead of through an OAuth token authorized by the user
+func UsesServiceAccount() bool {
+    return mttcConfig.Get().ServiceAccountFile != ""
+}
+
+// getServiceAccountConfig loads the service account's JSON key
+func getServiceAccountConfig() (*jwt.Config, error) {
+    b, err := ioutil.ReadFile(mttcConfig.Get().ServiceAccountFile)
+    if err != nil {
+        return nil, errors.Wrap(err, "Unable to read the service account key")
+    }
+
+    config, err := google.JWTConfigFromJSON(b, sheetsScope)
+    // XXX: if err == nil, errors.Wrap returns nil as well!
+    return config, errors.Wrap(err, "Unable to parse the service account key")
+}
+
+// getServiceAccountClient retrieves a client authenticated as the service
+// account. Its tokens are requested through JWT whenever they expire.
+func getServiceAccountClient() (*http.Client, error) {
+    config, err := getServiceAccountConfig()
+    if err != nil {
+        return nil, err
+    }
+    return config.Client(context.Background()), nil
+}
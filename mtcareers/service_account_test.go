@@ -0,0 +1,118 @@
+package mtcareers
+
+import (
+    "crypto/rand"
+    "crypto/rsa"
+    "crypto/x509"
+    "encoding/json"
+    "encoding/pem"
+    "fmt"
+    "github.com/SirGFM/MTTitleCard/config"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "testing"
+)
+
+// setupServiceAccount writes a service account key, whose tokens are issued
+// by a fake token endpoint, and configures the server to use it. Returns the
+// fake server, which also accepts API requests carrying the issued token.
+func setupServiceAccount(t *testing.T) *httptest.Server {
+    key, err := rsa.GenerateKey(rand.Reader, 2048)
+    if err != nil {
+        t.Fatalf("Failed to generate the key: %+v", err)
+    }
+    der, err := x509.MarshalPKCS8PrivateKey(key)
+    if err != nil {
+        t.Fatalf("Failed to encode the key: %+v", err)
+    }
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+        req.ParseForm()
+        if req.PostFormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" ||
+                req.PostFormValue("assertion") == "" {
+            http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(w, `{"access_token":"sa-token","token_type":"Bearer","expires_in":3600}`)
+    })
+    mux.HandleFunc("/api", func(w http.ResponseWriter, req *http.Request) {
+        if req.Header.Get("Authorization") != "Bearer sa-token" {
+            http.Error(w, "Unauthorized", http.StatusUnauthorized)
+            return
+        }
+        w.WriteHeader(http.StatusOK)
+    })
+    ts := httptest.NewServer(mux)
+    t.Cleanup(ts.Close)
+
+    b, _ := json.Marshal(map[string]string {
+        "type": "service_account",
+        "client_email": "mttc@example.iam.gserviceaccount.com",
+        "private_key_id": "key-id",
+        "private_key": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+        "token_uri": ts.URL + "/token",
+    })
+    path := filepath.Join(t.TempDir(), "service-account.json")
+    err = ioutil.WriteFile(path, b, 0600)
+    if err != nil {
+        t.Fatalf("Failed to write the key: %+v", err)
+    }
+
+    prev := config.Get()
+    cfg := prev
+    cfg.ServiceAccountFile = path
+    cfg.CredentialFile = filepath.Join(t.TempDir(), "missing.json")
+    cfg.TokenFile = filepath.Join(t.TempDir(), "missing.json")
+    config.LoadConfig(cfg)
+    t.Cleanup(func() {
+        config.LoadConfig(prev)
+    })
+
+    return ts
+}
+
+func TestServiceAccount(t *testing.T) {
+    ts := setupServiceAccount(t)
+
+    if !UsesServiceAccount() {
+        t.Fatalf("Expected the service account to be used")
+    }
+    if err := ValidToken(); err != nil {
+        t.Errorf("Expected the service account to be usable without a token: %+v", err)
+    }
+    if st := GetTokenStatus(); st.ServiceAccount != "mttc@example.iam.gserviceaccount.com" {
+        t.Errorf("Expected the service account in the token status, got %+v", st)
+    }
+
+    client, err := getSheetsClient()
+    if err != nil {
+        t.Fatalf("Failed to create the client: %+v", err)
+    }
+    resp, err := client.Get(ts.URL + "/api")
+    if err != nil {
+        t.Fatalf("Failed to access the API: %+v", err)
+    }
+    resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        t.Errorf("Expected the request to be authenticated, got %d", resp.StatusCode)
+    }
+}
+
+func TestBrokenServiceAccount(t *testing.T) {
+    setupServiceAccount(t)
+
+    cfg := config.Get()
+    cfg.ServiceAccountFile = filepath.Join(t.TempDir(), "missing.json")
+    config.LoadConfig(cfg)
+
+    if err := ValidToken(); err == nil {
+        t.Errorf("Expected a missing key to be reported")
+    }
+    if _, err := GetSheet(); err == nil {
+        t.Errorf("Expected a missing key to be reported")
+    }
+}
@@ -2,9 +2,11 @@ package mtcareers
 
 import (
     "encoding/json"
+    goErrors "errors"
     "fmt"
     "github.com/pkg/errors"
     mttcConfig "github.com/SirGFM/MTTitleCard/config"
+    "github.com/SirGFM/MTTitleCard/metrics"
     "golang.org/x/net/context"
     "golang.org/x/oauth2"
     "golang.org/x/oauth2/google"
@@ -13,14 +15,22 @@ import (
     "log"
     "net/http"
     "os"
+    "path/filepath"
     "strconv"
+    "time"
 )
 
+// sheetsScope is the access requested to Google's API. If modifying it,
+// delete your previously saved token.json.
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets.readonly"
+
+// ErrNoToken indicates that the server hasn't been authorized to access the
+// spreadsheet yet
+var ErrNoToken error = goErrors.New("OAuth token not found")
+
 type Sheet struct {
-    // Object used to access the spreadsheet
-    srv *sheets.Service
-    // ID of the spreadsheet being accessed
-    id string
+    // Object used to read ranges from the spreadsheet
+    reader rangeReader
     // Number of entrants through every tournament
     TotalEntrants int
     // Number of entrants on the latest tournament
@@ -29,20 +39,18 @@ type Sheet struct {
 
 // Retrieve a token, saves the token, then returns the generated client.
 func getClient(config *oauth2.Config) (*http.Client, error) {
-    authToken, err := CheckToken()
+    err := ValidToken()
     if err != nil {
-        return nil, errors.Wrap(err, "Failed to check OAuth token")
-    } else if authToken != "" {
-        serr := fmt.Sprintf("OAuth token not found!\n\nAccess http://localhost:%d\n", mttcConfig.Get().Port)
-        return nil, errors.New(serr)
+        return nil, err
     }
 
     // This shouldn't ever fail, since the token has already been checked
-    tok, err := tokenFromFile(mttcConfig.Get().TokenFile)
+    path := mttcConfig.Get().TokenFile
+    tok, err := tokenFromFile(path)
     if err != nil {
         return nil, errors.Wrap(err, "Failed to retrieve the OAuth token")
     }
-    return config.Client(context.Background(), tok), nil
+    return oauth2.NewClient(context.Background(), newTokenSource(config, tok, path)), nil
 }
 
 // Get the OAuth2 config for the given credential file
@@ -52,44 +60,116 @@ func getConfig() (*oauth2.Config, error) {
         return nil, errors.Wrap(err, "Unable to read client secret file")
     }
 
-    // If modifying these scopes, delete your previously saved token.json.
-    config, err := google.ConfigFromJSON(b, "https://www.googleapis.com/auth/spreadsheets.readonly")
-    // XXX: if err == nil, errors.Wrap returns nil as well!
-    return config, errors.Wrap(err, "Unable to parse client secret file to config")
+    config, err := google.ConfigFromJSON(b, sheetsScope)
+    if err != nil {
+        return nil, errors.Wrap(err, "Unable to parse client secret file to config")
+    }
+    config.RedirectURL = RedirectUrl()
+    return config, nil
 }
 
-// CheckToken. If it's not valid (mainly because it doesn't exist), generate
-// and return a auth URL.
-func CheckToken() (string, error) {
+// RedirectUrl retrieves the URL to which Google redirects the user after
+// authorizing the server, either as configured or on the local server
+func RedirectUrl() string {
+    if url := mttcConfig.Get().OAuthRedirectUrl; url != "" {
+        return url
+    }
+    return fmt.Sprintf("http://localhost:%d%s/oauth/callback",
+            mttcConfig.Get().Port, mttcConfig.Get().ServiceUri)
+}
+
+// ValidToken checks whether the OAuth token exists and wasn't revoked,
+// without starting a new authentication if it isn't usable. If using a service
+// account, checks that its key may be loaded instead. Local files don't need
+// any token.
+func ValidToken() error {
+    if UsesLocalFiles() {
+        return nil
+    }
+    if UsesServiceAccount() {
+        _, err := getServiceAccountConfig()
+        return err
+    }
+
     _, err := tokenFromFile(mttcConfig.Get().TokenFile)
     if err != nil {
-        config, err := getConfig()
-        if err != nil {
-            return "", errors.Wrap(err, "Unable to parse client secret file to config")
+        serr := fmt.Sprintf("Access http://localhost:%d to generate it", mttcConfig.Get().Port)
+        return errors.Wrap(ErrNoToken, serr)
+    }
+    if TokenRevoked() {
+        serr := fmt.Sprintf("Access http://localhost:%d to generate it again", mttcConfig.Get().Port)
+        return errors.Wrap(ErrTokenRevoked, serr)
+    }
+    return nil
+}
+
+// CheckToken. If it's not valid (mainly because it doesn't exist, or because
+// it was revoked), generate and return a auth URL, alongside the random state
+// within it. The state must be supplied back to SaveAuthentication. The
+// authentication uses PKCE, and redirects back to RedirectUrl.
+//
+// An expired token is refreshed right away, so a revoked token is detected
+// before the spreadsheet is accessed.
+func CheckToken() (string, string, error) {
+    path := mttcConfig.Get().TokenFile
+    tok, err := tokenFromFile(path)
+    if err == nil && !TokenRevoked() {
+        err = refreshToken(tok, path)
+        if errors.Cause(err) != ErrTokenRevoked {
+            if err != nil {
+                // Google may be temporarily unavailable, so keep the token
+                log.Printf("%+v", err)
+            }
+            return "", "", nil
         }
-        return config.AuthCodeURL("state-token", oauth2.AccessTypeOffline), nil
     }
-    return "", nil
+
+    config, err := getConfig()
+    if err != nil {
+        return "", "", errors.Wrap(err, "Unable to parse client secret file to config")
+    }
+    verifier := oauth2.GenerateVerifier()
+    state, err := newState(verifier)
+    if err != nil {
+        return "", "", err
+    }
+    url := config.AuthCodeURL(state, oauth2.AccessTypeOffline,
+            oauth2.S256ChallengeOption(verifier))
+    return url, state, nil
 }
 
-// SaveAuthentication finishes authenticating with OAuth2 and saves the token
-func SaveAuthentication(authCode string) error {
+// SaveAuthentication finishes authenticating with OAuth2 and saves the token.
+// state must be the one from the URL returned by CheckToken.
+func SaveAuthentication(authCode, state string) error {
+    verifier, err := verifyState(state)
+    if err != nil {
+        return err
+    }
     config, err := getConfig()
     if err != nil {
         return errors.Wrap(err, "Unable to parse client secret file to config")
     }
-    tok, err := config.Exchange(context.TODO(), authCode)
+    tok, err := config.Exchange(context.TODO(), authCode, oauth2.VerifierOption(verifier))
     if err != nil {
         return errors.Wrap(err, "Unable to retrieve token from web")
     }
     err = saveToken(mttcConfig.Get().TokenFile, tok)
-    // XXX: if err == nil, errors.Wrap returns nil as well!
-    return errors.Wrap(err, "Failed to save the OAuth token")
+    if err != nil {
+        return errors.Wrap(err, "Failed to save the OAuth token")
+    }
+    _revoked.set(nil)
+    return nil
 }
 
 // Request a token from the web, then returns the retrieved token.
 func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
-    authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
+    verifier := oauth2.GenerateVerifier()
+    state, err := newState(verifier)
+    if err != nil {
+        return nil, err
+    }
+    authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline,
+            oauth2.S256ChallengeOption(verifier))
     fmt.Printf("Go to the following link in your browser then type the "+
             "authorization code: \n%v\n", authURL)
 
@@ -99,7 +179,7 @@ func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
     }
 
     // XXX: if err == nil, errors.Wrap returns nil as well!
-    tok, err := config.Exchange(context.TODO(), authCode)
+    tok, err := config.Exchange(context.TODO(), authCode, oauth2.VerifierOption(verifier))
     return tok, errors.Wrap(err, "Unable to retrieve token from web")
 }
 
@@ -116,15 +196,31 @@ func tokenFromFile(file string) (*oauth2.Token, error) {
     return tok, errors.Wrap(err, "Failed to decode token JSON")
 }
 
-// Saves a token to a file path.
+// Saves a token to a file path. The token is written to a temporary file,
+// which then replaces the previous token, so the token file is never left
+// partially written.
 func saveToken(path string, token *oauth2.Token) error {
     log.Printf("Saving credential file to: %s", path)
-    f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+    f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path) + ".tmp")
     if err != nil {
         return errors.Wrap(err, "Unable to cache oauth token")
     }
-    defer f.Close()
-    json.NewEncoder(f).Encode(token)
+    tmp := f.Name()
+
+    err = json.NewEncoder(f).Encode(token)
+    if err == nil {
+        err = f.Sync()
+    }
+    if cerr := f.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Rename(tmp, path)
+    }
+    if err != nil {
+        os.Remove(tmp)
+        return errors.Wrap(err, "Unable to cache oauth token")
+    }
     return nil
 }
 
@@ -189,24 +285,79 @@ func cellToStr(cell interface{}) (str string, err error) {
     return
 }
 
-// GetSheet retrieves an object for accessing an spreadsheet
-func GetSheet() (*Sheet, error) {
-    // If modifying these scopes, delete your previously saved token.json.
+// getSheetsClient retrieves a client authenticated either as the service
+// account, if configured, or through the user's OAuth token
+func getSheetsClient() (*http.Client, error) {
+    if UsesServiceAccount() {
+        return getServiceAccountClient()
+    }
+
     config, err := getConfig()
     if err != nil {
         return nil, errors.Wrap(err, "Unable to parse client secret file to config")
     }
-    client, err := getClient(config)
+    return getClient(config)
+}
+
+// GetSheet retrieves an object for accessing an spreadsheet
+func GetSheet() (*Sheet, error) {
+    client, err := getSheetsClient()
     if err != nil {
         return nil, errors.Wrap(err, "Failed to initialize the Google API client")
     }
 
-    var sheet Sheet
-    sheet.srv, err = sheets.New(client)
+    srv, err := sheets.New(client)
     if err != nil {
         return nil, errors.Wrap(err, "Unable to retrieve Sheets accessor")
     }
-    sheet.id = mttcConfig.Get().MtCareerSpreasheet
 
-    return &sheet, nil
+    return &Sheet {
+        reader: &googleSheet {
+            srv: srv,
+            id: mttcConfig.Get().MtCareerSpreasheet,
+        },
+    }, nil
+}
+
+// rangeReader reads ranges from the spreadsheet, wherever it's stored
+type rangeReader interface {
+    // readRange retrieves the rows of r, from its first row up to lastRow
+    readRange(r mttcConfig.SheetRange, lastRow int) ([][]interface{}, error)
+}
+
+// googleSheet reads ranges from the spreadsheet through Google's API
+type googleSheet struct {
+    // Object used to access the spreadsheet
+    srv *sheets.Service
+    // ID of the spreadsheet being accessed
+    id string
+}
+
+// readRange downloads a range from the spreadsheet
+func (g *googleSheet) readRange(r mttcConfig.SheetRange, lastRow int) ([][]interface{}, error) {
+    _range := fmt.Sprintf(baseRange, r.SheetName, r.FirstColumn, r.FirstRow, r.LastColumn, lastRow)
+
+    start := time.Now()
+    resp, err := g.srv.Spreadsheets.Values.Get(g.id, _range).Do()
+    metrics.Upstream(metrics.Sheets, start, err)
+    if err != nil {
+        return nil, err
+    }
+    return resp.Values, nil
+}
+
+// getRange retrieves a range from the spreadsheet, from its first row up to
+// lastRow
+func (s *Sheet) getRange(r mttcConfig.SheetRange, lastRow int) ([][]interface{}, error) {
+    return s.reader.readRange(r, lastRow)
+}
+
+// Ping checks that the career data may be accessed, by downloading the (tiny)
+// range with the number of entrants
+func Ping() error {
+    sh, err := GetProvider()
+    if err != nil {
+        return err
+    }
+    return sh.GetTourneyInfo()
 }
@@ -0,0 +1,94 @@
+package mtcareers
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    goErrors "errors"
+    "github.com/pkg/errors"
+    "sync"
+    "time"
+)
+
+const (
+    // stateTTL is how long a generated OAuth state may be used
+    stateTTL = 15 * time.Minute
+    // maxStates is the maximum number of pending OAuth states. Older states
+    // are discarded first.
+    maxStates = 32
+)
+
+// ErrInvalidState indicates that the OAuth state wasn't generated by the
+// server, or that it already expired
+var ErrInvalidState error = goErrors.New("Invalid OAuth state")
+
+// pendingAuth is an authentication that may still be finished
+type pendingAuth struct {
+    // created is when the authentication started
+    created time.Time
+    // verifier is the PKCE code verifier used by the authentication
+    verifier string
+}
+
+// authStates stores every OAuth state that may still be used
+type authStates struct {
+    sync.Mutex
+    // pending maps each state to its authentication
+    pending map[string]pendingAuth
+}
+
+// _states stores every OAuth state that may still be used
+var _states = authStates {
+    pending: map[string]pendingAuth{},
+}
+
+// newState generates a random OAuth state, associated with the
+// authentication's PKCE verifier. The state must be verified by verifyState
+// when the authentication finishes.
+func newState(verifier string) (string, error) {
+    var buf [32]byte
+    _, err := rand.Read(buf[:])
+    if err != nil {
+        return "", errors.Wrap(err, "Failed to generate the OAuth state")
+    }
+    state := hex.EncodeToString(buf[:])
+
+    _states.Lock()
+    defer _states.Unlock()
+
+    now := time.Now()
+    for s, auth := range _states.pending {
+        if now.Sub(auth.created) >= stateTTL {
+            delete(_states.pending, s)
+        }
+    }
+    for len(_states.pending) >= maxStates {
+        var oldest string
+        for s, auth := range _states.pending {
+            if oldest == "" || auth.created.Before(_states.pending[oldest].created) {
+                oldest = s
+            }
+        }
+        delete(_states.pending, oldest)
+    }
+    _states.pending[state] = pendingAuth {
+        created: now,
+        verifier: verifier,
+    }
+
+    return state, nil
+}
+
+// verifyState checks that the state was generated by the server and that it
+// hasn't expired, returning the authentication's PKCE verifier. Each state may
+// only be used once.
+func verifyState(state string) (string, error) {
+    _states.Lock()
+    defer _states.Unlock()
+
+    auth, ok := _states.pending[state]
+    delete(_states.pending, state)
+    if !ok || time.Since(auth.created) >= stateTTL {
+        return "", errors.Wrap(ErrInvalidState, "The authentication must be started again")
+    }
+    return auth.verifier, nil
+}
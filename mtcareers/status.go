@@ -0,0 +1,115 @@
+package mtcareers
+
+import (
+    "github.com/pkg/errors"
+    mttcConfig "github.com/SirGFM/MTTitleCard/config"
+    "os"
+    "time"
+)
+
+// RangeStatus describes a range downloaded from the spreadsheet
+type RangeStatus struct {
+    // Name describes the range (e.g., "Tourney")
+    Name string
+    // SheetName is the sheet, within the spreadsheet, with the range
+    SheetName string
+    // Loaded is set if the range is currently cached
+    Loaded bool
+    // Rows is the number of downloaded rows
+    Rows int
+}
+
+// CacheStatus describes the downloaded spreadsheet
+type CacheStatus struct {
+    // Ranges lists every range that may be downloaded
+    Ranges []RangeStatus
+    // IdxToPlace converts an index in the standings row into a tournament
+    // placement
+    IdxToPlace []int
+    // Updated is when the spreadsheet was last downloaded
+    Updated time.Time
+}
+
+// TokenStatus describes the OAuth token used to access the spreadsheet
+type TokenStatus struct {
+    // Present is set if the token was found
+    Present bool
+    // Expiry is when the access token expires. It's renewed automatically if
+    // Refreshable is set.
+    Expiry time.Time
+    // Refreshable is set if the token may be renewed without the user
+    Refreshable bool
+    // Revoked is set if Google rejected the refresh token, so it must be
+    // generated again
+    Revoked bool
+    // LocalFiles is set if the data is read from local files, which don't use
+    // the OAuth token
+    LocalFiles bool
+    // ServiceAccount is the email of the service account used to access the
+    // spreadsheet, if any. Service accounts don't use the OAuth token.
+    ServiceAccount string
+    // Error describes why the token couldn't be loaded, if it's missing
+    Error string
+}
+
+// Status describes the currently downloaded spreadsheet
+func Status() CacheStatus {
+    _cache.Lock()
+    defer _cache.Unlock()
+
+    cfg := mttcConfig.Get()
+    return CacheStatus {
+        Ranges: []RangeStatus {
+            {"Tourney", cfg.UserInfo.SheetName, _cache.tourney != nil, len(_cache.tourney)},
+            {"Standings", cfg.StandingsInfo.SheetName, _cache.standings != nil, len(_cache.standings)},
+            {"MT Entrants", cfg.MtEntrantsInfo.SheetName, _cache.entrants != nil, len(_cache.entrants)},
+        },
+        IdxToPlace: _cache.idxToPlace,
+        Updated: _cache.updated,
+    }
+}
+
+// GetTokenStatus describes the OAuth token stored in the token file, or the
+// service account if one is configured
+func GetTokenStatus() TokenStatus {
+    if UsesLocalFiles() {
+        return TokenStatus {
+            LocalFiles: true,
+        }
+    } else if UsesServiceAccount() {
+        config, err := getServiceAccountConfig()
+        if err != nil {
+            return TokenStatus {
+                Error: err.Error(),
+            }
+        }
+        return TokenStatus {
+            Present: true,
+            Refreshable: true,
+            ServiceAccount: config.Email,
+        }
+    }
+
+    tok, err := tokenFromFile(mttcConfig.Get().TokenFile)
+    if err != nil {
+        return TokenStatus {
+            Error: err.Error(),
+        }
+    }
+
+    return TokenStatus {
+        Present: true,
+        Expiry: tok.Expiry,
+        Refreshable: tok.RefreshToken != "",
+        Revoked: TokenRevoked(),
+    }
+}
+
+// TokenAge retrieves how long ago the OAuth token was saved
+func TokenAge() (time.Duration, error) {
+    st, err := os.Stat(mttcConfig.Get().TokenFile)
+    if err != nil {
+        return 0, errors.Wrap(err, "Failed to check the token file")
+    }
+    return time.Since(st.ModTime()), nil
+}
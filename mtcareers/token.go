@@ -0,0 +1,135 @@
+package mtcareers
+
+import (
+    goErrors "errors"
+    "github.com/pkg/errors"
+    "golang.org/x/net/context"
+    mttcConfig "github.com/SirGFM/MTTitleCard/config"
+    "golang.org/x/oauth2"
+    "log"
+    "sync"
+    "time"
+)
+
+// ErrTokenRevoked indicates that Google rejected the refresh token (e.g.,
+// because the access was revoked or the token expired), so the token must be
+// generated again
+var ErrTokenRevoked error = goErrors.New("OAuth token revoked")
+
+// revokedToken records whether the stored refresh token was rejected
+type revokedToken struct {
+    sync.Mutex
+    // err is the error returned by Google, or nil if the token is usable
+    err error
+}
+
+// _revoked records whether the stored refresh token was rejected
+var _revoked revokedToken
+
+// set records that the token was rejected, or that it's usable again
+func (r *revokedToken) set(err error) {
+    r.Lock()
+    defer r.Unlock()
+    r.err = err
+}
+
+// get retrieves why the token was rejected, or nil if it's usable
+func (r *revokedToken) get() error {
+    r.Lock()
+    defer r.Unlock()
+    return r.err
+}
+
+// TokenRevoked checks whether Google rejected the stored refresh token, in
+// which case it must be generated again
+func TokenRevoked() bool {
+    return _revoked.get() != nil
+}
+
+// isRevoked checks whether Google rejected the refresh token itself, instead
+// of failing for some transient reason
+func isRevoked(err error) bool {
+    var rErr *oauth2.RetrieveError
+    return goErrors.As(err, &rErr) && rErr.ErrorCode == "invalid_grant"
+}
+
+// persistentTokenSource writes every refreshed token back to the token file,
+// so the refresh survives restarts
+type persistentTokenSource struct {
+    sync.Mutex
+    // src refreshes the token whenever it expires
+    src oauth2.TokenSource
+    // path to the token file
+    path string
+    // last is the most recently persisted token
+    last *oauth2.Token
+}
+
+// newTokenSource retrieves a token source that refreshes tok as needed,
+// saving it to path after each refresh
+func newTokenSource(config *oauth2.Config, tok *oauth2.Token, path string) oauth2.TokenSource {
+    return &persistentTokenSource {
+        src: config.TokenSource(context.Background(), tok),
+        path: path,
+        last: tok,
+    }
+}
+
+// Token retrieves a valid token, refreshing and saving it if it expired
+func (s *persistentTokenSource) Token() (*oauth2.Token, error) {
+    s.Lock()
+    defer s.Unlock()
+
+    tok, err := s.src.Token()
+    if isRevoked(err) {
+        log.Printf("The OAuth token was revoked: %+v", err)
+        _revoked.set(err)
+        return nil, errors.Wrap(ErrTokenRevoked, err.Error())
+    } else if err != nil {
+        return nil, errors.Wrap(err, "Failed to refresh the OAuth token")
+    }
+
+    if tok.AccessToken != s.last.AccessToken {
+        err = saveToken(s.path, tok)
+        if err != nil {
+            // The token may still be used, it just won't survive a restart
+            log.Printf("%+v", err)
+        }
+        s.last = tok
+    }
+    return tok, nil
+}
+
+// refreshToken refreshes the token, if it already expired, so a revoked
+// refresh token is detected before the spreadsheet is accessed
+func refreshToken(tok *oauth2.Token, path string) error {
+    if tok.Valid() || tok.RefreshToken == "" {
+        return nil
+    }
+    config, err := getConfig()
+    if err != nil {
+        return errors.Wrap(err, "Unable to parse client secret file to config")
+    }
+    _, err = newTokenSource(config, tok, path).Token()
+    return err
+}
+
+// CheckRevocation refreshes the stored OAuth token right away, even if it
+// hasn't expired yet, so a revoked token is detected (and reported by
+// TokenRevoked) without waiting for the spreadsheet to be accessed. Does
+// nothing if the spreadsheet isn't accessed through an OAuth token, or if the
+// token is already known to be revoked.
+func CheckRevocation() error {
+    if UsesLocalFiles() || UsesServiceAccount() || TokenRevoked() {
+        return nil
+    }
+
+    path := mttcConfig.Get().TokenFile
+    tok, err := tokenFromFile(path)
+    if err != nil {
+        // A missing token is already reported by ValidToken
+        return nil
+    }
+    tok.Expiry = time.Now().Add(-time.Minute)
+    return refreshToken(tok, path)
+}
@@ -0,0 +1,162 @@
+package mtcareers
+
+import (
+    "fmt"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "golang.org/x/oauth2"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// setupToken points the credential and token files to a temporary directory,
+// with an expired token refreshed through a fake token endpoint. The refresh
+// is rejected while revoked is set. Returns the path to the token file.
+func setupToken(t *testing.T, revoked *int32) string {
+    ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+        req.ParseForm()
+        if req.PostFormValue("grant_type") == "refresh_token" && atomic.LoadInt32(revoked) != 0 {
+            w.Header().Set("Content-Type", "application/json")
+            w.WriteHeader(http.StatusBadRequest)
+            fmt.Fprint(w, `{"error":"invalid_grant","error_description":"Token has been expired or revoked."}`)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(w, `{"access_token":"refreshed","token_type":"Bearer","expires_in":3600}`)
+    }))
+    t.Cleanup(ts.Close)
+
+    dir := t.TempDir()
+    cred := filepath.Join(dir, "credentials.json")
+    err := ioutil.WriteFile(cred, []byte(fmt.Sprintf(`{"installed":{"client_id":"id",`+
+            `"client_secret":"secret","redirect_uris":["http://localhost"],`+
+            `"auth_uri":"%s/auth","token_uri":"%s/token"}}`, ts.URL, ts.URL)), 0600)
+    if err != nil {
+        t.Fatalf("Failed to write the credentials: %+v", err)
+    }
+
+    prev := config.Get()
+    cfg := prev
+    cfg.CredentialFile = cred
+    cfg.TokenFile = filepath.Join(dir, "token.json")
+    config.LoadConfig(cfg)
+    t.Cleanup(func() {
+        config.LoadConfig(prev)
+        _revoked.set(nil)
+    })
+
+    err = saveToken(cfg.TokenFile, &oauth2.Token {
+        AccessToken: "expired",
+        RefreshToken: "refresh",
+        Expiry: time.Now().Add(-time.Hour),
+    })
+    if err != nil {
+        t.Fatalf("Failed to save the token: %+v", err)
+    }
+    return cfg.TokenFile
+}
+
+func TestRefreshedTokenIsSaved(t *testing.T) {
+    var revoked int32
+    path := setupToken(t, &revoked)
+
+    url, _, err := CheckToken()
+    if err != nil || url != "" {
+        t.Fatalf("Expected the token to be refreshed, got '%s' (%+v)", url, err)
+    }
+
+    tok, err := tokenFromFile(path)
+    if err != nil {
+        t.Fatalf("Failed to load the token: %+v", err)
+    }
+    if tok.AccessToken != "refreshed" || tok.RefreshToken != "refresh" {
+        t.Errorf("Expected the refreshed token to be saved, got %+v", tok)
+    }
+
+    files, _ := ioutil.ReadDir(filepath.Dir(path))
+    for _, f := range files {
+        if f.Name() != "token.json" && f.Name() != "credentials.json" {
+            t.Errorf("Unexpected file left behind: %s", f.Name())
+        }
+    }
+}
+
+func TestRevokedToken(t *testing.T) {
+    var revoked int32 = 1
+    path := setupToken(t, &revoked)
+
+    if err := ValidToken(); err != nil {
+        t.Fatalf("Expected the token to be usable before refreshing it: %+v", err)
+    }
+
+    url, state, err := CheckToken()
+    if err != nil || url == "" {
+        t.Fatalf("Expected the revoked token to be generated again, got '%s' (%+v)", url, err)
+    }
+    if !TokenRevoked() || !GetTokenStatus().Revoked {
+        t.Errorf("Expected the token to be reported as revoked")
+    }
+    if err := ValidToken(); errors.Cause(err) != ErrTokenRevoked {
+        t.Errorf("Expected the token to be rejected, got %+v", err)
+    }
+
+    // The token file is kept, so it may still be inspected
+    if _, err := os.Stat(path); err != nil {
+        t.Errorf("Expected the token file to be kept: %+v", err)
+    }
+
+    err = SaveAuthentication("code", state)
+    if err != nil {
+        t.Fatalf("Failed to save the new token: %+v", err)
+    }
+    if TokenRevoked() || ValidToken() != nil {
+        t.Errorf("Expected the new token to be usable")
+    }
+
+    tok, err := tokenFromFile(path)
+    if err != nil || tok.AccessToken != "refreshed" {
+        t.Errorf("Expected the new token to be saved, got %+v (%+v)", tok, err)
+    }
+}
+
+func TestCheckRevocation(t *testing.T) {
+    var revoked int32
+    path := setupToken(t, &revoked)
+
+    // The token is still valid, so it's only refreshed because it's checked
+    err := saveToken(path, &oauth2.Token {
+        AccessToken: "valid",
+        RefreshToken: "refresh",
+        Expiry: time.Now().Add(time.Hour),
+    })
+    if err != nil {
+        t.Fatalf("Failed to save the token: %+v", err)
+    }
+
+    err = CheckRevocation()
+    if err != nil || TokenRevoked() {
+        t.Fatalf("Expected the token to be usable, got %+v", err)
+    }
+    tok, err := tokenFromFile(path)
+    if err != nil || tok.AccessToken != "refreshed" {
+        t.Errorf("Expected the token to be refreshed, got %+v (%+v)", tok, err)
+    }
+
+    atomic.StoreInt32(&revoked, 1)
+    err = CheckRevocation()
+    if errors.Cause(err) != ErrTokenRevoked {
+        t.Errorf("Expected the token to be revoked, got %+v", err)
+    }
+    if !TokenRevoked() || !GetTokenStatus().Revoked {
+        t.Errorf("Expected the token to be reported as revoked")
+    }
+    if err := ValidToken(); errors.Cause(err) != ErrTokenRevoked {
+        t.Errorf("Expected the token to be rejected, got %+v", err)
+    }
+}
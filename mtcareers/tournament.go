@@ -0,0 +1,186 @@
+package mtcareers
+
+import (
+    "fmt"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "sort"
+    "strings"
+)
+
+// MT stores the info of a single tournament
+type MT struct {
+    // Name of the tournament (e.g., "MT14")
+    Name string
+    // Number of entrants in the tournament
+    Entrants int
+    // Number of entrants whose first tournament was this one
+    NewPlayers int
+}
+
+// Champion is a player that has won at least one tournament
+type Champion struct {
+    // The username (the same for SRL and MT Tournament)
+    Username string
+    // Number of tournaments won by the player
+    Titles int
+}
+
+// Tournament stores series-wide info, through every tournament
+type Tournament struct {
+    // Every tournament, from the first to the latest
+    MTs []MT
+    // Number of entrants through every tournament
+    TotalEntrants int
+    // Number of entrants on the latest tournament
+    LatestEntrants int
+    // Number of distinct players through every tournament
+    UniquePlayers int
+    // Number of entrants on the latest tournament that had joined a previous one
+    ReturningPlayers int
+    // Number of entrants on the latest tournament that hadn't joined any
+    // previous one
+    NewPlayers int
+    // Every player that won a tournament, sorted by their number of titles
+    Champions []Champion
+}
+
+// loadEntrants downloads the name and number of entrants of each MT, if it
+// isn't cached (or if it has expired)
+func (c *sheetCache) loadEntrants(s *Sheet) (entrants [][]interface{}, err error) {
+    c.Lock()
+    defer c.Unlock()
+
+    c.expire()
+
+    if c.entrants == nil {
+        info := config.Get().MtEntrantsInfo
+        values, gerr := s.getRange(info, info.FirstRow+1)
+        if gerr != nil {
+            err = errors.Wrap(gerr, "Unable to retrieve the entrants of each MT from sheet")
+            return
+        }
+
+        c.entrants = values
+    }
+
+    return c.entrants, nil
+}
+
+// mtName normalizes the name of a tournament, so names from different sheets
+// may be compared (e.g., ".MT14" and "mt14 ")
+func mtName(name string) string {
+    return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "."))
+}
+
+// entrantsToMTs converts the downloaded entrants of each MT into a list of
+// MTs. Tournaments without a name are skipped.
+func entrantsToMTs(entrants [][]interface{}) ([]MT, error) {
+    if len(entrants) < 2 {
+        return nil, nil
+    }
+
+    var mts []MT
+    names, counts := entrants[0], entrants[1]
+    for i, cell := range names {
+        name := strings.TrimPrefix(strings.TrimSpace(colToStr(cell)), ".")
+        if name == "" || i >= len(counts) || colToStr(counts[i]) == "" {
+            // Either not a tournament or one that hasn't started yet
+            continue
+        }
+        count, err := cellToInt(counts[i])
+        if err != nil {
+            return nil, errors.Wrap(err, fmt.Sprintf("Failed to parse the number of entrants in %s", name))
+        }
+        mts = append(mts, MT {
+            Name: name,
+            Entrants: count,
+        })
+    }
+
+    return mts, nil
+}
+
+// getChampions lists every player with at least one title in the standings,
+// from the most to the least titles
+func getChampions(standings [][]interface{}, idxToPlace []int) []Champion {
+    first := -1
+    for i, place := range idxToPlace {
+        if place == 1 {
+            first = i
+            break
+        }
+    }
+    if first == -1 {
+        return nil
+    }
+
+    var champions []Champion
+    for _, row := range standings {
+        if first >= len(row) {
+            continue
+        }
+        titles, err := cellToInt(row[first])
+        if err != nil {
+            // Skip the header and any blank cell
+            continue
+        } else if titles > 0 {
+            champions = append(champions, Champion {
+                Username: colToStr(row[0]),
+                Titles: titles,
+            })
+        }
+    }
+
+    sort.SliceStable(champions, func(i, j int) bool {
+        return champions[i].Titles > champions[j].Titles
+    })
+    return champions
+}
+
+// GetTournament retrieves series-wide info from the MT Career spreadsheet.
+// GetTourneyInfo must be called beforehand.
+func (s *Sheet) GetTournament() (t Tournament, err error) {
+    _, standings, idxToPlace, _, err := _cache.load(s)
+    if err != nil {
+        return
+    }
+    users, err := s.GetAllUsers()
+    if err != nil {
+        return
+    }
+    entrants, err := _cache.loadEntrants(s)
+    if err != nil {
+        return
+    }
+
+    t.TotalEntrants = s.TotalEntrants
+    t.LatestEntrants = s.LatestEntrants
+    t.UniquePlayers = len(users)
+
+    t.MTs, err = entrantsToMTs(entrants)
+    if err != nil {
+        return
+    }
+    for i := range t.MTs {
+        name := mtName(t.MTs[i].Name)
+        for _, u := range users {
+            if mtName(u.FirstMT) == name {
+                t.MTs[i].NewPlayers++
+            }
+        }
+    }
+    if len(t.MTs) > 0 {
+        latest := t.MTs[len(t.MTs)-1]
+        t.NewPlayers = latest.NewPlayers
+        if t.LatestEntrants == 0 {
+            t.LatestEntrants = latest.Entrants
+        }
+    }
+    if t.LatestEntrants > t.NewPlayers {
+        t.ReturningPlayers = t.LatestEntrants - t.NewPlayers
+    }
+
+    t.Champions = getChampions(standings, idxToPlace)
+    return
+}
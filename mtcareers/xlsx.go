@@ -0,0 +1,201 @@
+package mtcareers
+
+import (
+    "archive/zip"
+    "encoding/xml"
+    "github.com/pkg/errors"
+    "path"
+    "strings"
+)
+
+// xlsxWorkbook lists the sheets in a XLSX workbook (xl/workbook.xml)
+type xlsxWorkbook struct {
+    Sheets []struct {
+        Name string `xml:"name,attr"`
+        // Id of the relationship pointing to the sheet's file
+        Id string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+    } `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships maps relationships to files (xl/_rels/workbook.xml.rels)
+type xlsxRelationships struct {
+    Relationships []struct {
+        Id string `xml:"Id,attr"`
+        Target string `xml:"Target,attr"`
+    } `xml:"Relationship"`
+}
+
+// xlsxText is a (possibly rich) text, split in runs
+type xlsxText struct {
+    T string `xml:"t"`
+    Runs []struct {
+        T string `xml:"t"`
+    } `xml:"r"`
+}
+
+// xlsxSharedStrings lists the strings shared by every sheet
+// (xl/sharedStrings.xml)
+type xlsxSharedStrings struct {
+    Items []xlsxText `xml:"si"`
+}
+
+// xlsxWorksheet stores the cells in a sheet (e.g., xl/worksheets/sheet1.xml)
+type xlsxWorksheet struct {
+    Rows []struct {
+        // R is the row's number, starting at 1
+        R int `xml:"r,attr"`
+        Cells []struct {
+            // R is the cell's reference (e.g., "B12")
+            R string `xml:"r,attr"`
+            // T is the cell's type
+            T string `xml:"t,attr"`
+            V string `xml:"v"`
+            Is xlsxText `xml:"is"`
+        } `xml:"c"`
+    } `xml:"sheetData>row"`
+}
+
+// String retrieves the text, concatenating every run
+func (t xlsxText) String() string {
+    var b strings.Builder
+    b.WriteString(t.T)
+    for _, r := range t.Runs {
+        b.WriteString(r.T)
+    }
+    return b.String()
+}
+
+// decodeZipXml decodes a XML file within a zip archive
+func decodeZipXml(zr *zip.ReadCloser, name string, v interface{}) error {
+    for _, f := range zr.File {
+        if f.Name != name {
+            continue
+        }
+
+        rc, err := f.Open()
+        if err != nil {
+            return errors.Wrapf(err, "Failed to open '%s'", name)
+        }
+        defer rc.Close()
+        // XXX: if err == nil, errors.Wrap returns nil as well!
+        return errors.Wrapf(xml.NewDecoder(rc).Decode(v), "Failed to parse '%s'", name)
+    }
+    return errors.Errorf("Missing '%s'", name)
+}
+
+// cellColumn retrieves the column of a cell reference (e.g., 1 for "B12")
+func cellColumn(ref string) (int, error) {
+    end := strings.IndexAny(ref, "0123456789")
+    if end == -1 {
+        end = len(ref)
+    }
+    return columnIndex(ref[:end])
+}
+
+// readXlsx reads every cell in a sheet of a XLSX workbook
+func readXlsx(file, sheetName string) ([][]string, error) {
+    zr, err := zip.OpenReader(file)
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to open the workbook")
+    }
+    defer zr.Close()
+
+    var wb xlsxWorkbook
+    err = decodeZipXml(zr, "xl/workbook.xml", &wb)
+    if err != nil {
+        return nil, err
+    }
+    var relId string
+    for _, s := range wb.Sheets {
+        if s.Name == sheetName {
+            relId = s.Id
+        }
+    }
+    if relId == "" {
+        return nil, errors.Errorf("Sheet '%s' not found in '%s'", sheetName, file)
+    }
+
+    var rels xlsxRelationships
+    err = decodeZipXml(zr, "xl/_rels/workbook.xml.rels", &rels)
+    if err != nil {
+        return nil, err
+    }
+    var sheetFile string
+    for _, r := range rels.Relationships {
+        if r.Id != relId {
+            continue
+        }
+        // Targets are relative to "xl/", unless they are absolute
+        if strings.HasPrefix(r.Target, "/") {
+            sheetFile = strings.TrimPrefix(r.Target, "/")
+        } else {
+            sheetFile = path.Join("xl", r.Target)
+        }
+    }
+    if sheetFile == "" {
+        return nil, errors.Errorf("Sheet '%s' has no file in '%s'", sheetName, file)
+    }
+
+    // Workbooks without any text don't have shared strings
+    var sst xlsxSharedStrings
+    if err := decodeZipXml(zr, "xl/sharedStrings.xml", &sst); err != nil {
+        sst.Items = nil
+    }
+
+    var ws xlsxWorksheet
+    err = decodeZipXml(zr, sheetFile, &ws)
+    if err != nil {
+        return nil, err
+    }
+
+    var records [][]string
+    for _, row := range ws.Rows {
+        rowIdx := len(records)
+        if row.R > maxRows {
+            return nil, errors.Errorf("Invalid row %d in the sheet '%s'", row.R, sheetName)
+        } else if row.R > 0 {
+            rowIdx = row.R - 1
+        }
+        for len(records) <= rowIdx {
+            records = append(records, nil)
+        }
+
+        var cells []string
+        for _, c := range row.Cells {
+            // Cells without a reference follow the previous one
+            col := len(cells)
+            if c.R != "" {
+                col, err = cellColumn(c.R)
+                if err != nil {
+                    return nil, errors.Wrapf(err, "Invalid cell in the sheet '%s'", sheetName)
+                }
+            }
+
+            var val string
+            switch c.T {
+            case "s":
+                idx, err := cellToInt(c.V)
+                if err != nil || idx < 0 || idx >= len(sst.Items) {
+                    return nil, errors.Errorf("Invalid shared string in the cell '%s' of the sheet '%s'", c.R, sheetName)
+                }
+                val = sst.Items[idx].String()
+            case "inlineStr":
+                val = c.Is.String()
+            case "b":
+                val = formatBool(c.V == "1")
+            case "str", "e":
+                val = c.V
+            default:
+                val = formatNumber(c.V)
+            }
+
+            for len(cells) <= col {
+                cells = append(cells, "")
+            }
+            cells[col] = val
+        }
+        records[rowIdx] = cells
+    }
+
+    return records, nil
+}
@@ -0,0 +1,92 @@
+package mtcareers
+
+import (
+    "archive/zip"
+    "github.com/SirGFM/MTTitleCard/config"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// writeZip writes a zip archive with the supplied files
+func writeZip(t *testing.T, path string, files map[string]string) {
+    f, err := os.Create(path)
+    if err != nil {
+        t.Fatalf("Failed to create '%s': %+v", path, err)
+    }
+    defer f.Close()
+
+    zw := zip.NewWriter(f)
+    for name, data := range files {
+        w, err := zw.Create(name)
+        if err != nil {
+            t.Fatalf("Failed to create '%s': %+v", name, err)
+        }
+        w.Write([]byte(data))
+    }
+    err = zw.Close()
+    if err != nil {
+        t.Fatalf("Failed to write '%s': %+v", path, err)
+    }
+}
+
+// xlsxSheet wraps the rows of a XLSX worksheet
+func xlsxSheet(rows string) string {
+    return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + rows + `</sheetData></worksheet>`
+}
+
+func TestXlsxWorkbook(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "careers.xlsx")
+    writeZip(t, path, map[string]string {
+        "xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>
+<sheet name="Stats" sheetId="1" r:id="rId1"/>
+<sheet name="Career" sheetId="2" r:id="rId2"/>
+<sheet name="Standings" sheetId="3" r:id="rId3"/>
+</sheets>
+</workbook>`,
+        "xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet3.xml"/>
+</Relationships>`,
+        "xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>.MT1</t></si>
+<si><r><t>Al</t></r><r><t>ice</t></r></si>
+<si><t>Name</t></si>
+</sst>`,
+        "xl/worksheets/sheet1.xml": xlsxSheet(`
+<row r="1"><c r="A1" t="inlineStr"><is><t>MT1</t></is></c><c r="B1" t="inlineStr"><is><t>MT9</t></is></c><c r="C1" t="inlineStr"><is><t>MT10</t></is></c></row>
+<row r="2"><c r="A2"><v>5</v></c><c r="B2"><v>3</v></c></row>
+<row r="3"><c r="A3" t="inlineStr"><is><t>Total</t></is></c><c r="C3"><v>10</v></c><c r="D3" t="b"><v>1</v></c><c r="F3"><v>2</v></c></row>`),
+        "xl/worksheets/sheet2.xml": xlsxSheet(`
+<row r="1"><c r="A1" t="inlineStr"><is><t>First MT</t></is></c><c r="B1" t="s"><v>2</v></c><c r="C1" t="inlineStr"><is><t>MTs</t></is></c><c r="D1" t="inlineStr"><is><t>Wins</t></is></c><c r="E1" t="inlineStr"><is><t>Losses</t></is></c><c r="H1" t="inlineStr"><is><t>Draft Points</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>0</v></c><c r="B2" t="s"><v>1</v></c><c r="C2"><v>3</v></c><c r="D2"><v>10</v></c><c r="E2"><v>4</v></c><c r="H2"><v>12.5</v></c></row>
+<row><c t="str"><v>MT9</v></c><c t="inlineStr"><is><t>Bob</t></is></c><c><v>1</v></c><c><v>0</v></c><c><v>2</v></c><c r="H3"><v>0</v></c></row>`),
+        "xl/worksheets/sheet3.xml": xlsxSheet(`
+<row r="1"><c r="A1" t="s"><v>2</v></c><c r="B1" t="inlineStr"><is><t>MTs</t></is></c><c r="C1" t="inlineStr"><is><t>1st</t></is></c><c r="D1" t="inlineStr"><is><t>2nd</t></is></c><c r="E1" t="inlineStr"><is><t>3rd</t></is></c><c r="F1" t="inlineStr"><is><t>4th</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>1</v></c><c r="B2"><v>3</v></c><c r="C2"><v>0</v></c><c r="D2"><v>1</v></c><c r="E2"><v>1.9999999999999998</v></c><c r="F2"><v>0</v></c></row>
+<row r="3"><c r="A3" t="inlineStr"><is><t>Bob</t></is></c><c r="B3"><v>1</v></c><c r="C3"><v>0</v></c><c r="D3"><v>0</v></c><c r="E3"><v>0</v></c><c r="F3"><v>0</v></c></row>`),
+    })
+
+    setupLocalConfig(t, func(cfg *config.Config) {
+        cfg.WorkbookFile = path
+        // The workbook takes precedence over the exports
+        cfg.CsvFiles = map[string]string {
+            "Career": filepath.Join(t.TempDir(), "missing.csv"),
+        }
+    })
+    checkLocalData(t)
+
+    records, err := readXlsx(path, "Stats")
+    if err != nil || len(records) != 3 || records[2][3] != "TRUE" || records[1][0] != "5" {
+        t.Errorf("Unexpected cells: %+v (%+v)", records, err)
+    }
+    if _, err := readXlsx(path, "Missing"); err == nil {
+        t.Errorf("Expected a missing sheet to be reported")
+    }
+}
@@ -0,0 +1,256 @@
+package page
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
+    "fmt"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "log"
+    "net/http"
+    "net/url"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+// maxUpstreamErrors is the number of upstream errors kept for the admin page
+const maxUpstreamErrors = 20
+
+// upstreamError is a failure while accessing SRL, Twitch or Google
+type upstreamError struct {
+    // When the error happened
+    When time.Time
+    // Source is whatever was being retrieved (e.g., the username)
+    Source string
+    // Error describes what went wrong
+    Error string
+}
+
+// errorLog stores the most recent upstream errors
+type errorLog struct {
+    sync.Mutex
+    // entries from the oldest to the most recent error
+    entries []upstreamError
+}
+
+// _upstreamErrors stores the most recent upstream errors
+var _upstreamErrors errorLog
+
+// record an error, if it was caused by an upstream service. Users missing
+// from SRL or from the spreadsheet aren't recorded.
+func (l *errorLog) record(source string, err error) {
+    if status, _ := errorStatus(err); status < 500 {
+        return
+    }
+
+    l.Lock()
+    defer l.Unlock()
+
+    l.entries = append(l.entries, upstreamError {
+        When: time.Now(),
+        Source: source,
+        Error: err.Error(),
+    })
+    if len(l.entries) > maxUpstreamErrors {
+        l.entries = l.entries[len(l.entries) - maxUpstreamErrors:]
+    }
+}
+
+// recent lists every stored error, from the most recent to the oldest
+func (l *errorLog) recent() []upstreamError {
+    l.Lock()
+    defer l.Unlock()
+
+    list := make([]upstreamError, len(l.entries))
+    for i, e := range l.entries {
+        list[len(list) - 1 - i] = e
+    }
+    return list
+}
+
+// CachedPlayer describes a user in the cache
+type CachedPlayer struct {
+    Username string
+    // Age of the cached data
+    Age time.Duration
+    // Stale is set if the user expired and must be downloaded again
+    Stale bool
+}
+
+// snapshot lists every cached user, sorted by their username
+func (c *playerCache) snapshot() []CachedPlayer {
+    c.Lock()
+    defer c.Unlock()
+
+    ttl := config.Get().CacheExpiration()
+    list := make([]CachedPlayer, 0, len(c.entries))
+    for _, e := range c.entries {
+        age := time.Since(e.updated)
+        list = append(list, CachedPlayer {
+            Username: e.player.Data.Username,
+            Age: age.Round(time.Second),
+            Stale: ttl != 0 && age >= ttl,
+        })
+    }
+
+    sort.Slice(list, func(i, j int) bool {
+        return cacheKey(list[i].Username) < cacheKey(list[j].Username)
+    })
+    return list
+}
+
+// AdminData is supplied to the admin page
+type AdminData struct {
+    // Players currently cached
+    Players []CachedPlayer
+    // Sheet describes the downloaded spreadsheet
+    Sheet mtcareers.CacheStatus
+    // SheetAge is the age of the downloaded spreadsheet, if any
+    SheetAge time.Duration
+    // Token describes the OAuth token used to access the spreadsheet
+    Token mtcareers.TokenStatus
+    // Errors lists the most recent upstream errors
+    Errors []upstreamError
+    // Message reports the result of the last action, if any
+    Message string
+    ServiceUri string
+}
+
+// sameOrigin verifies that the request was sent from one of the server's own
+// pages, as reported by its Origin header (or by its Referer, if there's no
+// Origin). Requests reporting neither (e.g., from curl) are accepted.
+func (r *request) sameOrigin() bool {
+    origin := r.req.Header.Get("Origin")
+    if origin == "" {
+        origin = r.req.Header.Get("Referer")
+    }
+    if origin == "" {
+        return true
+    }
+
+    u, err := url.Parse(origin)
+    if err != nil || u.Host == "" {
+        return false
+    } else if u.Host == r.req.Host {
+        return true
+    }
+    svc, err := url.Parse(config.Get().ServiceUri)
+    return err == nil && u.Host == svc.Host
+}
+
+// checkAdmin verifies that the request was sent by an admin, either through
+// HTTP basic authentication or through the admin token. Since browsers send
+// basic credentials on their own, requests changing the server's state through
+// those must also come from the server's own pages (so other sites can't forge
+// them). Otherwise, an error is sent to the client and false is returned.
+func (r *request) checkAdmin() bool {
+    cfg := config.Get()
+    if cfg.AdminPassword == "" && cfg.AdminToken == "" {
+        http.Error(r.w, "Admin access is disabled: set adminPassword or adminToken in the configuration", http.StatusForbidden)
+        return false
+    }
+
+    if cfg.AdminToken != "" {
+        auth := r.req.Header.Get("Authorization")
+        if strings.HasPrefix(auth, "Bearer ") {
+            tok := strings.TrimPrefix(auth, "Bearer ")
+            if subtle.ConstantTimeCompare([]byte(tok), []byte(cfg.AdminToken)) == 1 {
+                return true
+            }
+        }
+    }
+
+    if cfg.AdminPassword != "" {
+        user, pass, ok := r.req.BasicAuth()
+        userOk := subtle.ConstantTimeCompare([]byte(user), []byte(cfg.AdminUser)) == 1
+        passOk := subtle.ConstantTimeCompare([]byte(pass), []byte(cfg.AdminPassword)) == 1
+        if ok && userOk && passOk {
+            if r.req.Method != "GET" && !r.sameOrigin() {
+                http.Error(r.w, "Cross-origin requests are forbidden", http.StatusForbidden)
+                return false
+            }
+            return true
+        }
+        r.w.Header().Set("WWW-Authenticate", `Basic realm="MTTitleCard"`)
+    }
+    http.Error(r.w, "Unauthorized", http.StatusUnauthorized)
+    return false
+}
+
+// generateAdminPassword sets a random admin password, if no admin credential
+// was configured, so the admin pages are never left unprotected
+func generateAdminPassword() error {
+    cfg := config.Get()
+    if cfg.AdminPassword != "" || cfg.AdminToken != "" {
+        return nil
+    }
+
+    var buf [12]byte
+    _, err := rand.Read(buf[:])
+    if err != nil {
+        return errors.Wrap(err, "Failed to generate the admin password")
+    }
+    cfg.AdminPassword = hex.EncodeToString(buf[:])
+    err = config.LoadConfig(cfg)
+    if err != nil {
+        return err
+    }
+
+    log.Printf("No admin credentials configured! Use '%s' and '%s' to access the admin pages", cfg.AdminUser, cfg.AdminPassword)
+    return nil
+}
+
+// getAdmin returns the page describing the server's state
+func (r *request) getAdmin() {
+    if !r.checkAdmin() {
+        return
+    }
+
+    data := AdminData {
+        Players: _cache.snapshot(),
+        Sheet: mtcareers.Status(),
+        Token: mtcareers.GetTokenStatus(),
+        Errors: _upstreamErrors.recent(),
+        Message: r.req.URL.Query().Get("msg"),
+        ServiceUri: r.serviceUri,
+    }
+    if !data.Sheet.Updated.IsZero() {
+        data.SheetAge = time.Since(data.Sheet.Updated).Round(time.Second)
+    }
+
+    r.renderPage(r.p.adminPage, "text/html", data)
+}
+
+// postAdmin runs an action from the admin page (as every POST, it requires an
+// admin):
+//   - "purge": removes every user from the cache
+//   - "reload": downloads the spreadsheet again
+func (r *request) postAdmin(action string) {
+    var msg string
+    switch action {
+    case "purge":
+        PurgeAll()
+        msg = "Purged every cached player"
+    case "reload":
+        invalidateResults()
+        users, err := cachedUsers()
+        if err != nil {
+            serr := fmt.Sprintf("%+v", err)
+            log.Print(serr)
+            _upstreamErrors.record("spreadsheet", err)
+            msg = "Failed to download the spreadsheet: " + err.Error()
+        } else {
+            msg = fmt.Sprintf("Downloaded the spreadsheet (%d players)", len(users))
+        }
+    default:
+        http.Error(r.w, fmt.Sprintf("Unknown action '%s'", action), http.StatusNotFound)
+        return
+    }
+
+    log.Print(msg)
+    http.Redirect(r.w, r.req, r.serviceUri + "/admin?msg=" + url.QueryEscape(msg), http.StatusSeeOther)
+}
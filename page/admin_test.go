@@ -0,0 +1,259 @@
+package page
+
+import (
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "testing"
+)
+
+// setupAdmin configures the admin credentials used by postAsAdmin
+func setupAdmin(t *testing.T) {
+    cfg := config.Get()
+    cfg.AdminPassword = "secret"
+    cfg.AdminToken = "admin-token"
+    err := config.LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+}
+
+// postAsAdmin sends a form to the server, authenticated as an admin
+func postAsAdmin(client *http.Client, uri string, form url.Values) (*http.Response, error) {
+    req, err := http.NewRequest("POST", uri, strings.NewReader(form.Encode()))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.Header.Set("Authorization", "Bearer admin-token")
+    return client.Do(req)
+}
+
+func TestStateChangesRequireAdmin(t *testing.T) {
+    setupCache(t, 0, nil)
+    setupAdmin(t)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    for _, tc := range []struct {
+        method string
+        uri string
+    } {
+        {"POST", "/refresh/GFM"},
+        {"POST", "/producer"},
+        {"POST", "/admin/purge"},
+        {"POST", "/"},
+        {"DELETE", "/GFM"},
+        {"DELETE", "/"},
+    } {
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, httptest.NewRequest(tc.method, tc.uri, nil))
+        if w.Code != http.StatusUnauthorized {
+            t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.uri, http.StatusUnauthorized, w.Code)
+        }
+    }
+
+    req := httptest.NewRequest("DELETE", "/GFM", nil)
+    req.Header.Set("Authorization", "Bearer admin-token")
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, req)
+    if w.Code != http.StatusNoContent {
+        t.Errorf("Expected the admin to purge the user, got %d", w.Code)
+    }
+}
+
+func TestAdminAuth(t *testing.T) {
+    setupCache(t, 0, nil)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/admin", nil))
+    if w.Code != http.StatusForbidden {
+        t.Errorf("Expected the admin page to be disabled, got %d", w.Code)
+    }
+
+    cfg := config.Get()
+    cfg.AdminPassword = "secret"
+    config.LoadConfig(cfg)
+
+    for _, tc := range []struct {
+        user, pass string
+        status int
+    } {
+        {"", "", http.StatusUnauthorized},
+        {"admin", "wrong", http.StatusUnauthorized},
+        {"someone", "secret", http.StatusUnauthorized},
+        {"admin", "secret", http.StatusOK},
+    } {
+        req := httptest.NewRequest("GET", "/admin", nil)
+        if tc.user != "" {
+            req.SetBasicAuth(tc.user, tc.pass)
+        }
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, req)
+        if w.Code != tc.status {
+            t.Errorf("%s:%s: expected status %d, got %d", tc.user, tc.pass, tc.status, w.Code)
+        }
+    }
+}
+
+func TestAdminPage(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        switch username {
+        case "GFM":
+            var p Player
+            p.Data.Username = username
+            return p, nil
+        case "Unknown":
+            return Player{}, errors.Wrap(mtcareers.ErrUserNotFound, "Failed to find 'Unknown'")
+        default:
+            return Player{}, errors.New("Upstream is down")
+        }
+    })
+    cfg := config.Get()
+    cfg.AdminPassword = "secret"
+    config.LoadConfig(cfg)
+
+    _upstreamErrors.Lock()
+    _upstreamErrors.entries = nil
+    _upstreamErrors.Unlock()
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+    for _, path := range []string{"/GFM", "/Unknown", "/Broken"} {
+        ps.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+    }
+
+    get := func() string {
+        req := httptest.NewRequest("GET", "/admin", nil)
+        req.SetBasicAuth("admin", "secret")
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, req)
+        if w.Code != http.StatusOK {
+            t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+        }
+        return w.Body.String()
+    }
+
+    body := get()
+    if !strings.Contains(body, "<td>GFM</td>") {
+        t.Errorf("Expected GFM to be listed as cached")
+    }
+    if !strings.Contains(body, "Upstream is down") {
+        t.Errorf("Expected the upstream error to be listed")
+    }
+    if strings.Contains(body, "Failed to find") {
+        t.Errorf("Expected missing players to not be listed as upstream errors")
+    }
+
+    req := httptest.NewRequest("POST", "/admin/purge", nil)
+    req.SetBasicAuth("admin", "secret")
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, req)
+    if w.Code != http.StatusSeeOther {
+        t.Fatalf("Expected a redirect after purging, got %d", w.Code)
+    }
+    if body := get(); strings.Contains(body, "<td>GFM</td>") {
+        t.Errorf("Expected the cache to be purged")
+    }
+
+    req = httptest.NewRequest("POST", "/admin/purge", nil)
+    w = httptest.NewRecorder()
+    ps.ServeHTTP(w, req)
+    if w.Code != http.StatusUnauthorized {
+        t.Errorf("Expected unauthorized actions to fail, got %d", w.Code)
+    }
+}
+
+func TestAdminCrossOrigin(t *testing.T) {
+    setupCache(t, 0, nil)
+    setupAdmin(t)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    for _, tc := range []struct {
+        header, value string
+        bearer bool
+        status int
+    } {
+        {"", "", false, http.StatusSeeOther},
+        {"Origin", "http://example.com", false, http.StatusSeeOther},
+        {"Referer", "http://example.com/admin", false, http.StatusSeeOther},
+        {"Origin", "https://evil.example", false, http.StatusForbidden},
+        {"Origin", "null", false, http.StatusForbidden},
+        {"Referer", "https://evil.example/form", false, http.StatusForbidden},
+        {"Origin", "https://evil.example", true, http.StatusSeeOther},
+    } {
+        req := httptest.NewRequest("POST", "/admin/purge", nil)
+        if tc.bearer {
+            req.Header.Set("Authorization", "Bearer admin-token")
+        } else {
+            req.SetBasicAuth("admin", "secret")
+        }
+        if tc.header != "" {
+            req.Header.Set(tc.header, tc.value)
+        }
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, req)
+        if w.Code != tc.status {
+            t.Errorf("%s: %s (bearer: %v): expected status %d, got %d", tc.header, tc.value, tc.bearer, tc.status, w.Code)
+        }
+    }
+}
+
+func TestDeleteRejectsRoutes(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        var p Player
+        p.Data.Username = username
+        return p, nil
+    })
+    setupAdmin(t)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+    _, err = getPlayer("GFM", "GFM")
+    if err != nil {
+        t.Fatalf("Failed to get user: %+v", err)
+    }
+
+    for _, tc := range []struct {
+        uri string
+        status int
+    } {
+        {"/admin", http.StatusBadRequest},
+        {"/metrics", http.StatusBadRequest},
+        {"/api/v1/players/GFM", http.StatusBadRequest},
+        {"/GFM/other", http.StatusNotFound},
+        {"/GFM/", http.StatusNotFound},
+    } {
+        req := httptest.NewRequest("DELETE", tc.uri, nil)
+        req.Header.Set("Authorization", "Bearer admin-token")
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, req)
+        if w.Code != tc.status {
+            t.Errorf("DELETE %s: expected status %d, got %d", tc.uri, tc.status, w.Code)
+        }
+    }
+
+    if _, state := _cache.get("GFM"); state != cacheFresh {
+        t.Errorf("Expected the cache to be kept")
+    }
+}
@@ -0,0 +1,73 @@
+package page
+
+import (
+    "encoding/json"
+    "fmt"
+    "log"
+    "net/http"
+    "strings"
+)
+
+// apiVersion is the only version of the API currently supported
+const apiVersion = "v1"
+
+// apiError is the body returned by the API on failures
+type apiError struct {
+    Error string `json:"error"`
+}
+
+// writeJson encodes v as the response's body
+func (r *request) writeJson(status int, v interface{}) {
+    r.w.Header().Set("Content-Type", "application/json")
+    r.w.WriteHeader(status)
+    err := json.NewEncoder(r.w).Encode(v)
+    if err != nil {
+        log.Printf("Failed to encode the JSON response: %+v", err)
+    }
+}
+
+// apiFail reports an error back to the API client
+func (r *request) apiFail(status int, msg string) {
+    r.writeJson(status, apiError {
+        Error: msg,
+    })
+}
+
+// getApi handles GET requests within the API. path is the requested resource,
+// without the "api/" prefix (e.g., "v1/players/GFM").
+func (r *request) getApi(path string) {
+    version, path := splitPath(path)
+    if version != apiVersion {
+        r.apiFail(http.StatusNotFound, fmt.Sprintf("Unknown API version '%s'", version))
+        return
+    }
+
+    resource, path := splitPath(path)
+    switch resource {
+    case "players":
+        if path == "" || strings.Contains(path, "/") {
+            r.apiFail(http.StatusNotFound, "Expected a single player: /api/v1/players/{name}")
+            return
+        }
+        r.getApiPlayer(path)
+    case "leaderboard":
+        r.getApiLeaderboard()
+    case "tournament":
+        r.getApiTournament()
+    default:
+        r.apiFail(http.StatusNotFound, fmt.Sprintf("Unknown resource '%s'", resource))
+    }
+}
+
+// getApiPlayer returns every information retrieved for a given user
+func (r *request) getApiPlayer(username string) {
+    p, err := getPlayer(username, username)
+    if err != nil {
+        log.Printf("%+v", err)
+        status, _ := errorStatus(err)
+        r.apiFail(status, err.Error())
+        return
+    }
+
+    r.writeJson(http.StatusOK, p)
+}
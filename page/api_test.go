@@ -0,0 +1,64 @@
+package page
+
+import (
+    "encoding/json"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestApiPlayer(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        switch username {
+        case "GFM":
+            var p Player
+            p.Data.Username = "GFM"
+            p.Career.Username = "GFM"
+            p.Career.WinCount = 4
+            p.Srl.Channel = "gfmchannel"
+            return p, nil
+        case "Unknown":
+            return Player{}, errors.Wrap(mtcareers.ErrUserNotFound, "Failed to find 'Unknown'")
+        default:
+            return Player{}, errors.New("Upstream is down")
+        }
+    })
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    for _, tc := range []struct {
+        path string
+        status int
+    } {
+        {"/api/v1/players/GFM", http.StatusOK},
+        {"/api/v1/players/Unknown", http.StatusNotFound},
+        {"/api/v1/players/Broken", http.StatusBadGateway},
+        {"/api/v1/players/", http.StatusNotFound},
+        {"/api/v2/players/GFM", http.StatusNotFound},
+        {"/api/v1/games/GFM", http.StatusNotFound},
+    } {
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+
+        if w.Code != tc.status {
+            t.Errorf("%s: expected status %d, got %d", tc.path, tc.status, w.Code)
+        } else if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+            t.Errorf("%s: expected a JSON response, got '%s'", tc.path, ct)
+        }
+    }
+
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/players/GFM", nil))
+    var p Player
+    err = json.NewDecoder(w.Body).Decode(&p)
+    if err != nil {
+        t.Fatalf("Failed to decode the response: %+v", err)
+    } else if p.Data.Username != "GFM" || p.Career.WinCount != 4 || p.Srl.Channel != "gfmchannel" {
+        t.Fatalf("Got the wrong player: %+v", p)
+    }
+}
@@ -0,0 +1,294 @@
+package page
+
+import (
+    "github.com/SirGFM/MTTitleCard/config"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "image"
+    "log"
+    "strings"
+    "sync"
+    "time"
+)
+
+// cacheState describes whether a cached user may be served as is
+type cacheState int
+
+const (
+    // cacheMiss indicates that the user must be downloaded before being served
+    cacheMiss cacheState = iota
+    // cacheFresh indicates that the user is up-to-date
+    cacheFresh
+    // cacheStale indicates that the user may be served, but it should be
+    // downloaded again
+    cacheStale
+)
+
+// cacheEntry stores a parsed user and when it was downloaded
+type cacheEntry struct {
+    // username used to download the user
+    username string
+    // player is the parsed user
+    player Player
+    // updated is when the user was last downloaded
+    updated time.Time
+    // avatar is the user's avatar, as displayed in the PNG title card, or nil
+    // if it couldn't be downloaded
+    avatar image.Image
+    // avatarUrl is from where avatar was downloaded, or empty if it wasn't
+    // downloaded yet
+    avatarUrl string
+}
+
+// fetchCall tracks a download in progress, so concurrent look ups for the
+// same user may wait for it instead of downloading the user again
+type fetchCall struct {
+    // done is closed as soon as the download finishes
+    done chan struct{}
+    // player is the downloaded user, valid only after done is closed
+    player Player
+    // err is the result of the download, valid only after done is closed
+    err error
+    // changed is set if the downloaded user differs from the cached one,
+    // valid only after done is closed
+    changed bool
+    // generation of the cache when the download started
+    generation int
+    // purged is set if the user got purged while being downloaded, so the
+    // download isn't stored
+    purged bool
+}
+
+// playerCache stores every downloaded and parsed user
+type playerCache struct {
+    sync.Mutex
+    // entries maps a lower case username to its cached data
+    entries map[string]*cacheEntry
+    // calls maps a lower case username to its download in progress
+    calls map[string]*fetchCall
+    // generation is incremented whenever the cache gets purged, so downloads
+    // started before the purge don't get stored
+    generation int
+}
+
+// _cache of already downloaded and parsed users
+var _cache playerCache = playerCache {
+    entries: map[string]*cacheEntry{},
+    calls: map[string]*fetchCall{},
+}
+
+// fetchUser downloads and parses a user. It may be replaced on tests.
+var fetchUser func(srlUsername, username string) (Player, error) = fetchPlayer
+
+// cacheKey normalizes the username, since the spreadsheet is looked up
+// ignoring the case
+func cacheKey(username string) string {
+    return strings.ToLower(username)
+}
+
+// get a user from the cache, alongside whether it may be served. Users that
+// expired more than config.CacheMaxStale ago are removed from the cache.
+func (c *playerCache) get(username string) (Player, cacheState) {
+    c.Lock()
+    defer c.Unlock()
+
+    key := cacheKey(username)
+    e, ok := c.entries[key]
+    if !ok {
+        return Player{}, cacheMiss
+    }
+
+    ttl := config.Get().CacheExpiration()
+    maxStale := config.Get().CacheStaleLimit()
+    age := time.Since(e.updated)
+    if ttl == 0 || age < ttl {
+        return e.player, cacheFresh
+    } else if maxStale != 0 && age >= ttl + maxStale {
+        delete(c.entries, key)
+        return Player{}, cacheMiss
+    }
+    return e.player, cacheStale
+}
+
+// startFetch retrieves the download in progress for a user, or starts a new
+// one. Downloads started before the cache (or the user) got purged aren't
+// shared, since their result won't be stored. Returns true if the caller is
+// responsible for running the download.
+func (c *playerCache) startFetch(username string) (*fetchCall, bool) {
+    c.Lock()
+    defer c.Unlock()
+
+    key := cacheKey(username)
+    if call, ok := c.calls[key]; ok && call.generation == c.generation && !call.purged {
+        return call, false
+    }
+    call := &fetchCall {
+        done: make(chan struct{}),
+        generation: c.generation,
+    }
+    c.calls[key] = call
+    return call, true
+}
+
+// runFetch downloads a user, stores it in the cache and wakes up everyone
+// waiting for this download. If the user changed, it's also sent to every
+// event stream following it.
+func (c *playerCache) runFetch(call *fetchCall, srlUsername, username string) {
+    call.player, call.err = fetchUser(srlUsername, username)
+
+    c.Lock()
+    key := cacheKey(username)
+    if call.err == nil && call.generation == c.generation && !call.purged {
+        old, ok := c.entries[key]
+        call.changed = !ok || old.player.Data != call.player.Data
+        e := &cacheEntry {
+            username: username,
+            player: call.player,
+            updated: time.Now(),
+        }
+        // Keep the avatar, unless it changed or must be downloaded again
+        if ok && old.avatar != nil && old.avatarUrl == call.player.Data.Avatar {
+            e.avatar = old.avatar
+            e.avatarUrl = old.avatarUrl
+        }
+        c.entries[key] = e
+    }
+    // A newer download may have replaced this one, if the cache (or the user)
+    // got purged
+    if c.calls[key] == call {
+        delete(c.calls, key)
+    }
+    c.Unlock()
+
+    if call.err != nil {
+        _upstreamErrors.record(username, call.err)
+    }
+
+    if call.changed {
+        publishPlayer(username, call.player.Data)
+    }
+    close(call.done)
+}
+
+// fetch downloads a user, sharing the download with every concurrent look up
+// for the same user. Also returns whether the user changed.
+func (c *playerCache) fetch(srlUsername, username string) (Player, bool, error) {
+    call, isOwner := c.startFetch(username)
+    if isOwner {
+        c.runFetch(call, srlUsername, username)
+    } else {
+        <-call.done
+    }
+    return call.player, call.changed, call.err
+}
+
+// revalidate downloads an expired user in the background, unless it's already
+// being downloaded. On failure, the expired data is kept in the cache.
+func (c *playerCache) revalidate(srlUsername, username string) {
+    call, isOwner := c.startFetch(username)
+    if !isOwner {
+        return
+    }
+
+    go func() {
+        c.runFetch(call, srlUsername, username)
+        if call.err != nil {
+            log.Printf("Failed to refresh '%s', keeping the cached data: %+v", username, call.err)
+        }
+    } ()
+}
+
+// usernames lists the username used to download each cached user
+func (c *playerCache) usernames() []string {
+    c.Lock()
+    defer c.Unlock()
+
+    list := make([]string, 0, len(c.entries))
+    for _, e := range c.entries {
+        list = append(list, e.username)
+    }
+    return list
+}
+
+// resultCache stores a value computed from the whole spreadsheet (e.g., every
+// user, for the leaderboard), so it isn't downloaded on every request. Just
+// like users, it expires after config.CacheTTL.
+type resultCache struct {
+    sync.Mutex
+    // value is the cached result, valid only if updated isn't zero
+    value interface{}
+    // updated is when the value was last computed
+    updated time.Time
+}
+
+// Results computed from the whole spreadsheet
+var (
+    // _allUsers caches every user, as returned by fetchAllUsers
+    _allUsers resultCache
+    // _tournament caches the series-wide info, as returned by fetchTournament
+    _tournament resultCache
+)
+
+// get the cached value, computing it through fetch if it's missing or if it
+// expired. The lock is held while fetching, so concurrent look ups share a
+// single download. Failures aren't cached.
+func (c *resultCache) get(fetch func() (interface{}, error)) (interface{}, error) {
+    c.Lock()
+    defer c.Unlock()
+
+    ttl := config.Get().CacheExpiration()
+    if !c.updated.IsZero() && (ttl == 0 || time.Since(c.updated) < ttl) {
+        return c.value, nil
+    }
+
+    v, err := fetch()
+    if err != nil {
+        return nil, err
+    }
+    c.value = v
+    c.updated = time.Now()
+    return v, nil
+}
+
+// invalidate the cached value, so it's computed again on its next access
+func (c *resultCache) invalidate() {
+    c.Lock()
+    defer c.Unlock()
+
+    c.value = nil
+    c.updated = time.Time{}
+}
+
+// invalidateResults removes every result computed from the spreadsheet,
+// alongside the downloaded spreadsheet itself
+func invalidateResults() {
+    _allUsers.invalidate()
+    _tournament.invalidate()
+    mtcareers.Invalidate()
+}
+
+// Purge removes a single user from the cache, so it's downloaded again on its
+// next access. A download of the user already in progress isn't stored, while
+// every other user (and the downloaded spreadsheet) is kept.
+func Purge(username string) {
+    key := cacheKey(username)
+
+    _cache.Lock()
+    defer _cache.Unlock()
+
+    delete(_cache.entries, key)
+    if call, ok := _cache.calls[key]; ok {
+        call.purged = true
+        delete(_cache.calls, key)
+    }
+}
+
+// PurgeAll removes every user from the cache, alongside the downloaded
+// spreadsheet.
+func PurgeAll() {
+    _cache.Lock()
+    _cache.entries = map[string]*cacheEntry{}
+    _cache.generation++
+    _cache.Unlock()
+
+    invalidateResults()
+}
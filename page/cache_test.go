@@ -0,0 +1,224 @@
+package page
+
+import (
+    "github.com/SirGFM/MTTitleCard/config"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// setupCache resets the cache and replaces the downloader by fetch
+func setupCache(t *testing.T, ttl int, fetch func(string, string) (Player, error)) {
+    cfg := config.GetDefault()
+    cfg.CacheTTL = ttl
+    cfg.CacheMaxStale = 0
+    err := config.LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+
+    _cache.Lock()
+    _cache.entries = map[string]*cacheEntry{}
+    _cache.calls = map[string]*fetchCall{}
+    _cache.Unlock()
+    _allUsers.invalidate()
+    _tournament.invalidate()
+
+    oldFetch := fetchUser
+    fetchUser = fetch
+    t.Cleanup(func() {
+        fetchUser = oldFetch
+    })
+}
+
+func TestConcurrentLookupsShareDownload(t *testing.T) {
+    var count int32
+    release := make(chan struct{})
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        atomic.AddInt32(&count, 1)
+        <-release
+        return Player{Data: Data{Username: username, Wins: 3}}, nil
+    })
+
+    var wg sync.WaitGroup
+    for i := 0; i < 16; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            p, err := getPlayer("GFM", "GFM")
+            if err != nil {
+                t.Errorf("Failed to get user: %+v", err)
+            } else if p.Data.Wins != 3 {
+                t.Errorf("Got wrong data: %+v", p)
+            }
+        } ()
+    }
+
+    // Give every goroutine the chance to wait on the download
+    time.Sleep(50 * time.Millisecond)
+    close(release)
+    wg.Wait()
+
+    if n := atomic.LoadInt32(&count); n != 1 {
+        t.Fatalf("Expected a single download, got %d", n)
+    }
+
+    // Differently cased names should hit the cache as well
+    _, err := getPlayer("gfm", "gfm")
+    if err != nil {
+        t.Fatalf("Failed to get cached user: %+v", err)
+    } else if n := atomic.LoadInt32(&count); n != 1 {
+        t.Fatalf("Expected the user to be cached, got %d downloads", n)
+    }
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+    var wins int32
+    refreshed := make(chan struct{}, 1)
+    setupCache(t, 1, func(srlUsername, username string) (Player, error) {
+        w := atomic.AddInt32(&wins, 1)
+        if w > 1 {
+            refreshed <- struct{}{}
+        }
+        return Player{Data: Data{Username: username, Wins: int(w)}}, nil
+    })
+
+    p, err := getPlayer("GFM", "GFM")
+    if err != nil || p.Data.Wins != 1 {
+        t.Fatalf("Failed to get user: %+v (%+v)", p, err)
+    }
+
+    // Expire the entry manually, instead of waiting for the TTL
+    _cache.Lock()
+    _cache.entries[cacheKey("GFM")].updated = time.Now().Add(-time.Hour)
+    _cache.Unlock()
+
+    p, err = getPlayer("GFM", "GFM")
+    if err != nil || p.Data.Wins != 1 {
+        t.Fatalf("Expected the stale user to be served: %+v (%+v)", p, err)
+    }
+
+    select {
+    case <-refreshed:
+    case <-time.After(time.Second):
+        t.Fatal("User wasn't refreshed in the background")
+    }
+
+    // Wait for the refreshed entry to be stored
+    for i := 0; i < 100; i++ {
+        p, _ = _cache.get("GFM")
+        if p.Data.Wins == 2 {
+            return
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    t.Fatalf("Refreshed user wasn't cached: %+v", p)
+}
+
+func TestPurge(t *testing.T) {
+    var count int32
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        atomic.AddInt32(&count, 1)
+        return Player{Data: Data{Username: username}}, nil
+    })
+
+    for _, name := range []string{"GFM", "GFM", "Other"} {
+        _, err := getPlayer(name, name)
+        if err != nil {
+            t.Fatalf("Failed to get user: %+v", err)
+        }
+    }
+    if n := atomic.LoadInt32(&count); n != 2 {
+        t.Fatalf("Expected 2 downloads, got %d", n)
+    }
+
+    Purge("gfm")
+    if _, state := _cache.get("GFM"); state != cacheMiss {
+        t.Fatalf("Purged user is still cached")
+    } else if _, state := _cache.get("Other"); state != cacheFresh {
+        t.Fatalf("Purging a user removed another one")
+    }
+
+    PurgeAll()
+    if _, state := _cache.get("Other"); state != cacheMiss {
+        t.Fatalf("User is still cached after purging everything")
+    }
+}
+
+func TestFetchAfterPurge(t *testing.T) {
+    var count int32
+    release := make(chan struct{})
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        n := atomic.AddInt32(&count, 1)
+        if n == 1 {
+            <-release
+        }
+        return Player{Data: Data{Username: username, Wins: int(n)}}, nil
+    })
+
+    // Start a download, and purge the cache while it's in progress
+    old := make(chan Player)
+    go func() {
+        p, _, _ := _cache.fetch("GFM", "GFM")
+        old <- p
+    } ()
+    for atomic.LoadInt32(&count) == 0 {
+        time.Sleep(time.Millisecond)
+    }
+    Purge("GFM")
+
+    p, _, err := _cache.fetch("GFM", "GFM")
+    if err != nil || p.Data.Wins != 2 {
+        t.Fatalf("Expected a new download after purging: %+v (%+v)", p, err)
+    }
+    close(release)
+    if p := <-old; p.Data.Wins != 1 {
+        t.Fatalf("Expected the old download to finish: %+v", p)
+    }
+
+    if p, state := _cache.get("GFM"); state != cacheFresh || p.Data.Wins != 2 {
+        t.Fatalf("Expected the new download to be cached: %+v", p)
+    }
+    _cache.Lock()
+    calls := len(_cache.calls)
+    _cache.Unlock()
+    if calls != 0 {
+        t.Fatalf("Expected every download to be finished, got %d", calls)
+    }
+}
+
+func TestPurgeKeepsOtherUsers(t *testing.T) {
+    var count int32
+    release := make(chan struct{})
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        if username == "Other" {
+            atomic.AddInt32(&count, 1)
+            <-release
+        }
+        return Player{Data: Data{Username: username}}, nil
+    })
+    leaderboard := setupLeaderboard(t, nil)
+    cachedUsers()
+
+    // Start downloading another user, and purge a user while it's in progress
+    done := make(chan struct{})
+    go func() {
+        _cache.fetch("Other", "Other")
+        close(done)
+    } ()
+    for atomic.LoadInt32(&count) == 0 {
+        time.Sleep(time.Millisecond)
+    }
+    Purge("GFM")
+    close(release)
+    <-done
+
+    if _, state := _cache.get("Other"); state != cacheFresh {
+        t.Errorf("Expected the other user's download to be cached")
+    }
+    cachedUsers()
+    if n := atomic.LoadInt32(leaderboard); n != 1 {
+        t.Errorf("Expected the leaderboard to be kept, got %d downloads", n)
+    }
+}
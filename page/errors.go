@@ -0,0 +1,87 @@
+package page
+
+import (
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "github.com/SirGFM/MTTitleCard/srlprofile"
+    "net/http"
+)
+
+// Files, within a theme's directory, that override each of the error pages
+const (
+    notInSheetPage = "error_not_in_sheet.html"
+    notOnSrlPage = "error_not_on_srl.html"
+    outagePage = "error_outage.html"
+    noTokenPage = "error_no_token.html"
+)
+
+// ErrorData is supplied to every error page. The embedded Data is a
+// placeholder for the requested player, so error pages may still look like a
+// title card.
+type ErrorData struct {
+    Data
+    // Status is the HTTP status code sent with the page
+    Status int
+    // Error describes what went wrong
+    Error string
+}
+
+// errorStatus maps an error to the HTTP status code and the page reporting it:
+//   - 404 if the player isn't in the MT Career spreadsheet
+//   - 404 if the player isn't registered on SRL
+//   - 503 if the server hasn't been authorized to access the spreadsheet, or
+//     if the authorization was revoked
+//   - 429 if the player was refreshed too recently, without any error page
+//     (since it's only reported to admins, never to viewers)
+//   - 502 if any upstream service failed
+func errorStatus(err error) (int, string) {
+    switch errors.Cause(err) {
+    case mtcareers.ErrUserNotFound:
+        return http.StatusNotFound, notInSheetPage
+    case srlprofile.ErrUserNotFound:
+        return http.StatusNotFound, notOnSrlPage
+    case mtcareers.ErrNoToken, mtcareers.ErrTokenRevoked:
+        return http.StatusServiceUnavailable, noTokenPage
+    case ErrRefreshTooSoon:
+        return http.StatusTooManyRequests, ""
+    default:
+        return http.StatusBadGateway, outagePage
+    }
+}
+
+// mysteryPlayer is a placeholder for a user that couldn't be retrieved (most
+// likely someone still missing from the spreadsheet)
+func mysteryPlayer(username string) Player {
+    p := Player {
+        Data: Data {
+            Channel: "It's a mystery",
+            Username: username,
+            Joined: "Just now!",
+            WinRate: "0",
+            HighestPlacement: "This round",
+            MtCount: 1,
+        },
+    }
+    p.Career.Username = username
+    p.Career.HighestPosition = mtcareers.NoPlacement
+    return p
+}
+
+// renderError reports, through the request theme's error page, that a user
+// couldn't be retrieved
+func (r *request) renderError(username string, err error) {
+    status, page := errorStatus(err)
+    if page == "" {
+        http.Error(r.w, err.Error(), status)
+        return
+    }
+
+    data := ErrorData {
+        Data: mysteryPlayer(username).Data,
+        Status: status,
+        Error: err.Error(),
+    }
+    data.ServiceUri = r.serviceUri
+
+    r.renderStatus(page, "text/html", status, data)
+}
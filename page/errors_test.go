@@ -0,0 +1,108 @@
+package page
+
+import (
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "github.com/SirGFM/MTTitleCard/srlprofile"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// setupErrors makes every user fail with an error chosen by their username
+func setupErrors(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        switch username {
+        case "NotInSheet":
+            return Player{}, errors.Wrap(mtcareers.ErrUserNotFound, "Failed to find 'NotInSheet'")
+        case "NotOnSrl":
+            return Player{}, errors.Wrap(srlprofile.ErrUserNotFound, "Failed to get SRL Profile")
+        case "NoToken":
+            return Player{}, errors.Wrap(mtcareers.ErrNoToken, "Failed to initialize the Google API client")
+        default:
+            return Player{}, errors.New("Upstream is down")
+        }
+    })
+}
+
+func TestErrorPages(t *testing.T) {
+    setupErrors(t)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    for _, tc := range []struct {
+        path string
+        status int
+        body string
+    } {
+        {"/NotInSheet", http.StatusNotFound, "It&#39;s a mystery"},
+        {"/NotOnSrl", http.StatusNotFound, "SpeedRunsLive"},
+        {"/NoToken", http.StatusServiceUnavailable, "must be authorized"},
+        {"/Outage", http.StatusBadGateway, "try again later"},
+        {"/NotInSheet.svg", http.StatusNotFound, "It&#39;s a mystery"},
+        {"/NoToken.png", http.StatusServiceUnavailable, ""},
+        {"/vs/GFM/NotInSheet", http.StatusBadGateway, "NotInSheet"},
+        {"/api/v1/players/NoToken", http.StatusServiceUnavailable, "OAuth token not found"},
+        {"/api/v1/players/NotOnSrl", http.StatusNotFound, "User not found on SRL"},
+    } {
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+
+        if w.Code != tc.status {
+            t.Errorf("%s: expected status %d, got %d", tc.path, tc.status, w.Code)
+        } else if !strings.Contains(w.Body.String(), tc.body) {
+            t.Errorf("%s: expected '%s' in the response, got '%s'", tc.path, tc.body, w.Body.String())
+        }
+    }
+}
+
+func TestErrorPageOverride(t *testing.T) {
+    setupErrors(t)
+
+    dir := t.TempDir()
+    writeFile(t, filepath.Join(dir, "custom", outagePage),
+            `{{.Username}} failed with {{.Status}}`)
+
+    cfg := config.Get()
+    cfg.ThemesDir = dir
+    config.LoadConfig(cfg)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/theme/custom/GFM", nil))
+    if w.Code != http.StatusBadGateway {
+        t.Errorf("Expected status 502, got %d", w.Code)
+    } else if body := w.Body.String(); body != "GFM failed with 502" {
+        t.Errorf("Expected the overridden error page, got '%s'", body)
+    }
+}
+
+func TestRateLimitHasNoErrorPage(t *testing.T) {
+    setupCache(t, 0, nil)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    w := httptest.NewRecorder()
+    r := request {
+        p: ps.(*pageServer),
+        w: w,
+        req: httptest.NewRequest("GET", "/GFM", nil),
+    }
+    r.renderError("GFM", errors.Wrap(ErrRefreshTooSoon, "Failed to refresh 'GFM'"))
+    if w.Code != http.StatusTooManyRequests || strings.Contains(w.Body.String(), "unavailable") {
+        t.Errorf("Expected the rate limit to not use the outage page, got %d: %s", w.Code, w.Body.String())
+    }
+}
@@ -0,0 +1,261 @@
+package page
+
+import (
+    "encoding/json"
+    goErrors "errors"
+    "fmt"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "log"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// heartbeatInterval is how often a comment is sent to idle event streams, so
+// proxies don't close the connection
+const heartbeatInterval = 30 * time.Second
+
+// minRefreshInterval is how long a user must wait before being refreshed
+// again, since each refresh downloads the spreadsheet once again. It may be
+// replaced on tests.
+var minRefreshInterval = 10 * time.Second
+
+// ErrRefreshTooSoon indicates that the user was refreshed too recently
+var ErrRefreshTooSoon error = goErrors.New("User was refreshed too recently")
+
+// refreshLimiter tracks when each user was last refreshed
+type refreshLimiter struct {
+    sync.Mutex
+    // last maps a lower case username to when it was last refreshed
+    last map[string]time.Time
+}
+
+// _refreshes tracks when each user was last refreshed
+var _refreshes refreshLimiter = refreshLimiter {
+    last: map[string]time.Time{},
+}
+
+// allow checks whether a user may be refreshed, and records the refresh if so
+func (l *refreshLimiter) allow(username string) bool {
+    l.Lock()
+    defer l.Unlock()
+
+    now := time.Now()
+    for key, last := range l.last {
+        if now.Sub(last) >= minRefreshInterval {
+            delete(l.last, key)
+        }
+    }
+
+    key := cacheKey(username)
+    if _, ok := l.last[key]; ok {
+        return false
+    }
+    l.last[key] = now
+    return true
+}
+
+// event is a message sent to every subscriber of a topic
+type event struct {
+    // name of the event, sent as the Server-Sent Event's type
+    name string
+    // data is the JSON encoded payload of the event
+    data []byte
+}
+
+// broker forwards events to every subscriber of a topic
+type broker struct {
+    sync.Mutex
+    // subscribers maps each topic to the channels listening to it
+    subscribers map[string]map[chan event]struct{}
+}
+
+// _events forwards every update to the connected event streams
+var _events broker = broker {
+    subscribers: map[string]map[chan event]struct{}{},
+}
+
+// playerTopic returns the topic used to publish updates for a user
+func playerTopic(username string) string {
+    return "player/" + cacheKey(username)
+}
+
+// subscribe to a topic. The returned channel must be released with
+// unsubscribe.
+func (b *broker) subscribe(topic string) chan event {
+    b.Lock()
+    defer b.Unlock()
+
+    ch := make(chan event, 4)
+    subs, ok := b.subscribers[topic]
+    if !ok {
+        subs = map[chan event]struct{}{}
+        b.subscribers[topic] = subs
+    }
+    subs[ch] = struct{}{}
+    return ch
+}
+
+// unsubscribe from a topic
+func (b *broker) unsubscribe(topic string, ch chan event) {
+    b.Lock()
+    defer b.Unlock()
+
+    subs := b.subscribers[topic]
+    delete(subs, ch)
+    if len(subs) == 0 {
+        delete(b.subscribers, topic)
+    }
+}
+
+// newEvent encodes v as the payload of an event
+func newEvent(name string, v interface{}) (event, error) {
+    data, err := json.Marshal(v)
+    if err != nil {
+        return event{}, errors.Wrap(err, fmt.Sprintf("Failed to encode '%s' event", name))
+    }
+    return event{name, data}, nil
+}
+
+// publish an event to every subscriber of topic. Subscribers that are lagging
+// behind miss the event, instead of blocking the publisher.
+func (b *broker) publish(topic, name string, v interface{}) {
+    ev, err := newEvent(name, v)
+    if err != nil {
+        log.Printf("%+v", err)
+        return
+    }
+
+    b.Lock()
+    defer b.Unlock()
+
+    for ch := range b.subscribers[topic] {
+        select {
+        case ch <- ev:
+        default:
+            log.Printf("Dropping '%s' event for a lagging subscriber of '%s'", name, topic)
+        }
+    }
+}
+
+// publishPlayer sends the user's updated data to every event stream
+// following it
+func publishPlayer(username string, data Data) {
+    _events.publish(playerTopic(username), "player", data)
+}
+
+// Refresh downloads a user once again and sends it to every event stream
+// following it, even if nothing changed. Each user may only be refreshed once
+// every minRefreshInterval.
+func Refresh(username string) (Data, error) {
+    if !_refreshes.allow(username) {
+        return Data{}, errors.Wrap(ErrRefreshTooSoon, fmt.Sprintf("Failed to refresh '%s'", username))
+    }
+
+    // Make sure the spreadsheet itself gets downloaded again
+    Purge(username)
+    mtcareers.Invalidate()
+
+    p, changed, err := _cache.fetch(username, username)
+    if err != nil {
+        return Data{}, err
+    } else if !changed {
+        // Updated data was already published when it got cached
+        publishPlayer(username, p.Data)
+    }
+    return p.Data, nil
+}
+
+// stream sends every event from topic to the client as Server-Sent Events,
+// until the client disconnects. first, if not nil, is sent as soon as the
+// client connects. onTick, if not nil, is called on each interval, so the
+// stream may check for updates.
+func (r *request) stream(topic string, first *event, interval time.Duration, onTick func()) {
+    flusher, ok := r.w.(http.Flusher)
+    if !ok {
+        http.Error(r.w, "Streaming isn't supported", http.StatusInternalServerError)
+        return
+    }
+
+    ch := _events.subscribe(topic)
+    defer _events.unsubscribe(topic, ch)
+
+    r.w.Header().Set("Content-Type", "text/event-stream")
+    r.w.Header().Set("Cache-Control", "no-cache")
+    r.w.Header().Set("Connection", "keep-alive")
+    r.w.WriteHeader(http.StatusOK)
+    if first != nil {
+        fmt.Fprintf(r.w, "event: %s\ndata: %s\n\n", first.name, first.data)
+    }
+    flusher.Flush()
+
+    heartbeat := time.NewTicker(heartbeatInterval)
+    defer heartbeat.Stop()
+    var tick <-chan time.Time
+    if onTick != nil && interval > 0 {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        tick = ticker.C
+    }
+
+    for {
+        select {
+        case <-r.req.Context().Done():
+            return
+        case ev := <-ch:
+            fmt.Fprintf(r.w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+        case <-heartbeat.C:
+            fmt.Fprint(r.w, ": keep-alive\n\n")
+        case <-tick:
+            onTick()
+            continue
+        }
+        flusher.Flush()
+    }
+}
+
+// getEvents streams every update to a user's data. The user is checked on
+// each config.CacheTTL, so it's downloaded again once it expires.
+func (r *request) getEvents(username string) {
+    if username == "" {
+        http.Error(r.w, "Expected a player: /events/{name}", http.StatusNotFound)
+        return
+    }
+
+    r.stream(playerTopic(username), nil, config.Get().CacheExpiration(), func() {
+        // Expired users get refreshed in the background, and published
+        // as soon as they change
+        _, err := getPlayer(username, username)
+        if err != nil {
+            log.Printf("Failed to check '%s' for updates: %+v", username, err)
+        }
+    })
+}
+
+// postRefresh downloads a user once again, sending it to every event stream
+// following it (as every POST, it requires an admin)
+func (r *request) postRefresh(username string) {
+    if username == "" {
+        http.Error(r.w, "Expected a player: /refresh/{name}", http.StatusNotFound)
+        return
+    }
+
+    data, err := Refresh(username)
+    if errors.Cause(err) == ErrRefreshTooSoon {
+        wait := int(minRefreshInterval / time.Second)
+        r.w.Header().Set("Retry-After", strconv.Itoa(wait))
+        http.Error(r.w, fmt.Sprintf("'%s' was refreshed too recently: try again in %d seconds", username, wait), http.StatusTooManyRequests)
+        return
+    } else if err != nil {
+        serr := fmt.Sprintf("%+v", err)
+        status, _ := errorStatus(err)
+        http.Error(r.w, serr, status)
+        log.Print(serr)
+        return
+    }
+
+    r.writeJson(http.StatusOK, data)
+}
@@ -0,0 +1,123 @@
+package page
+
+import (
+    "bufio"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// setupRefreshInterval resets the refresh limit, replacing its interval
+func setupRefreshInterval(t *testing.T, interval time.Duration) {
+    old := minRefreshInterval
+    minRefreshInterval = interval
+    _refreshes.Lock()
+    _refreshes.last = map[string]time.Time{}
+    _refreshes.Unlock()
+    t.Cleanup(func() {
+        minRefreshInterval = old
+    })
+}
+
+func TestRefreshRateLimit(t *testing.T) {
+    var count int32
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        atomic.AddInt32(&count, 1)
+        return Player{Data: Data{Username: username}}, nil
+    })
+    setupAdmin(t)
+    setupRefreshInterval(t, time.Hour)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+    refresh := func(name string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest("POST", "/refresh/" + name, nil)
+        req.Header.Set("Authorization", "Bearer admin-token")
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, req)
+        return w
+    }
+
+    if w := refresh("GFM"); w.Code != http.StatusOK {
+        t.Fatalf("Failed to refresh the user: %d", w.Code)
+    }
+    w := refresh("gfm")
+    if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "3600" {
+        t.Errorf("Expected the second refresh to be limited, got %d", w.Code)
+    }
+    if body := w.Body.String(); !strings.Contains(body, "refreshed too recently") || strings.Contains(body, "unavailable") {
+        t.Errorf("Expected the rate limit to be reported as such, got '%s'", body)
+    }
+    if w := refresh("Other"); w.Code != http.StatusOK {
+        t.Errorf("Expected other users to be refreshed, got %d", w.Code)
+    }
+    if n := atomic.LoadInt32(&count); n != 2 {
+        t.Errorf("Expected 2 downloads, got %d", n)
+    }
+}
+
+func TestEventsOnRefresh(t *testing.T) {
+    var wins int32
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        var p Player
+        p.Data.Username = username
+        p.Data.Wins = int(atomic.AddInt32(&wins, 1))
+        return p, nil
+    })
+    setupAdmin(t)
+    setupRefreshInterval(t, 0)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+    ts := httptest.NewServer(ps)
+    defer ts.Close()
+
+    resp, err := ts.Client().Get(ts.URL + "/events/GFM")
+    if err != nil {
+        t.Fatalf("Failed to connect to the event stream: %+v", err)
+    }
+    defer resp.Body.Close()
+    if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+        t.Fatalf("Expected an event stream, got '%s'", ct)
+    }
+
+    // The user changes on every download, so each refresh is published
+    events := bufio.NewReader(resp.Body)
+    for expected := 1; expected <= 2; expected++ {
+        resp, err := postAsAdmin(ts.Client(), ts.URL + "/refresh/gfm", nil)
+        if err != nil {
+            t.Fatalf("Failed to refresh the user: %+v", err)
+        }
+        resp.Body.Close()
+
+        var name, data string
+        for data == "" {
+            line, err := events.ReadString('\n')
+            if err != nil {
+                t.Fatalf("Failed to read the event stream: %+v", err)
+            }
+            line = strings.TrimSpace(line)
+            if strings.HasPrefix(line, "event: ") {
+                name = strings.TrimPrefix(line, "event: ")
+            } else if strings.HasPrefix(line, "data: ") {
+                data = strings.TrimPrefix(line, "data: ")
+            }
+        }
+
+        var d Data
+        err = json.Unmarshal([]byte(data), &d)
+        if err != nil {
+            t.Fatalf("Failed to decode the event: %+v", err)
+        } else if name != "player" || d.Wins != expected {
+            t.Fatalf("Expected a 'player' event with %d wins, got '%s': %+v", expected, name, d)
+        }
+    }
+}
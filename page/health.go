@@ -0,0 +1,152 @@
+package page
+
+import (
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "log"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// pingInterval is how long the result of accessing the spreadsheet is reused
+// by the readiness check, so frequent probes don't exhaust Google's quota
+const pingInterval = 30 * time.Second
+
+// checkToken and pingSheet verify the access to the spreadsheet. They may be
+// replaced on tests.
+var checkToken func() error = mtcareers.ValidToken
+var pingSheet func() error = mtcareers.Ping
+
+// checkRevocation verifies whether the token was revoked. It may be replaced
+// on tests.
+var checkRevocation func() error = mtcareers.CheckRevocation
+
+// pingCache stores the result of the last access to the spreadsheet
+type pingCache struct {
+    sync.Mutex
+    // checked is when the spreadsheet was last accessed
+    checked time.Time
+    // err is the result of the last access
+    err error
+}
+
+// _ping stores the result of the last access to the spreadsheet
+var _ping pingCache
+
+// check accesses the spreadsheet, unless it was accessed within pingInterval.
+// The lock is held while accessing it, so concurrent probes share it.
+func (c *pingCache) check() error {
+    c.Lock()
+    defer c.Unlock()
+
+    if c.checked.IsZero() || time.Since(c.checked) >= pingInterval {
+        c.err = pingSheet()
+        c.checked = time.Now()
+    }
+    return c.err
+}
+
+// readyCheck is the result of a single readiness check
+type readyCheck struct {
+    Name string `json:"name"`
+    Ok bool `json:"ok"`
+    Error string `json:"error,omitempty"`
+}
+
+// readyStatus is returned by the readiness endpoint
+type readyStatus struct {
+    Ready bool `json:"ready"`
+    Checks []readyCheck `json:"checks"`
+}
+
+// checkTemplates verifies that every page has been parsed
+func (p *pageServer) checkTemplates() error {
+    p.themeLock.RLock()
+    defer p.themeLock.RUnlock()
+
+    if p.defaultTheme == nil {
+        return errors.New("The default theme wasn't loaded")
+    }
+    for _, page := range themePages {
+        if p.defaultTheme.pages[page.file] == nil {
+            return errors.New("Missing the default " + page.file)
+        }
+    }
+    if p.renewPage == nil || p.livePage == nil || p.producerPage == nil || p.adminPage == nil {
+        return errors.New("Some of the internal pages weren't parsed")
+    }
+    return nil
+}
+
+// getHealth reports that the server is running
+func (r *request) getHealth() {
+    r.w.Header().Set("Content-Type", "text/plain")
+    r.w.WriteHeader(http.StatusOK)
+    r.w.Write([]byte("ok\n"))
+}
+
+// getReady reports whether the server is able to serve title cards: the
+// configuration must be loaded, every template must be parsed, the OAuth token
+// must exist and the spreadsheet must be accessible.
+func (r *request) getReady() {
+    var status readyStatus
+
+    add := func(name string, err error) {
+        c := readyCheck {
+            Name: name,
+            Ok: err == nil,
+        }
+        if err != nil {
+            c.Error = err.Error()
+        }
+        status.Checks = append(status.Checks, c)
+    }
+
+    var err error
+    if !config.Loaded() {
+        err = errors.New("The configuration wasn't loaded")
+    }
+    add("config", err)
+    add("templates", r.p.checkTemplates())
+
+    err = checkToken()
+    add("token", err)
+    if err == nil {
+        add("sheets", _ping.check())
+    } else {
+        add("sheets", errors.New("Skipped, since there's no usable token"))
+    }
+
+    status.Ready = true
+    for _, c := range status.Checks {
+        status.Ready = status.Ready && c.Ok
+    }
+
+    if status.Ready {
+        r.writeJson(http.StatusOK, status)
+    } else {
+        r.writeJson(http.StatusServiceUnavailable, status)
+    }
+}
+
+// watchToken checks whether the token was revoked on each interval, until stop
+// gets closed. A revoked token is reported by /readyz and by the admin page,
+// instead of only when the spreadsheet is next accessed.
+func watchToken(interval time.Duration, stop chan struct{}) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-stop:
+            return
+        case <-ticker.C:
+            err := checkRevocation()
+            if err != nil {
+                log.Printf("Failed to check the OAuth token: %+v", err)
+            }
+        }
+    }
+}
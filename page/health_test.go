@@ -0,0 +1,140 @@
+package page
+
+import (
+    "encoding/json"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+// setupReady replaces the access to the spreadsheet for the duration of the
+// test, returning the number of times the spreadsheet was accessed
+func setupReady(t *testing.T, tokenErr, pingErr error) *int {
+    var pings int
+
+    oldCheck, oldPing := checkToken, pingSheet
+    checkToken = func() error {
+        return tokenErr
+    }
+    pingSheet = func() error {
+        pings++
+        return pingErr
+    }
+    resetPing := func() {
+        _ping.Lock()
+        _ping.checked = time.Time{}
+        _ping.Unlock()
+    }
+    resetPing()
+    t.Cleanup(func() {
+        checkToken, pingSheet = oldCheck, oldPing
+        resetPing()
+    })
+
+    return &pings
+}
+
+// getReadyStatus requests the readiness of the server
+func getReadyStatus(t *testing.T, ps PageServer) (int, readyStatus) {
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+
+    var status readyStatus
+    err := json.Unmarshal(w.Body.Bytes(), &status)
+    if err != nil {
+        t.Fatalf("Failed to decode the readiness: %+v", err)
+    }
+    return w.Code, status
+}
+
+func TestHealth(t *testing.T) {
+    setupCache(t, 0, nil)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+    if w.Code != http.StatusOK || w.Body.String() != "ok\n" {
+        t.Errorf("Expected the server to be healthy, got %d: %s", w.Code, w.Body.String())
+    }
+}
+
+func TestReady(t *testing.T) {
+    setupCache(t, 0, nil)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    pings := setupReady(t, nil, nil)
+    code, status := getReadyStatus(t, ps)
+    if code != http.StatusOK || !status.Ready || len(status.Checks) != 4 {
+        t.Errorf("Expected the server to be ready, got %d: %+v", code, status)
+    }
+    getReadyStatus(t, ps)
+    if *pings != 1 {
+        t.Errorf("Expected the spreadsheet to be accessed once, got %d", *pings)
+    }
+
+    for _, tc := range []struct {
+        tokenErr, pingErr error
+        failed string
+    } {
+        {mtcareers.ErrNoToken, nil, "token"},
+        {nil, errors.New("Quota exceeded"), "sheets"},
+    } {
+        setupReady(t, tc.tokenErr, tc.pingErr)
+        code, status := getReadyStatus(t, ps)
+        if code != http.StatusServiceUnavailable || status.Ready {
+            t.Errorf("Expected the server to not be ready, got %d: %+v", code, status)
+        }
+        for _, c := range status.Checks {
+            if c.Name == tc.failed && c.Ok {
+                t.Errorf("Expected check '%s' to fail: %+v", c.Name, status)
+            }
+        }
+    }
+}
+
+func TestWatchToken(t *testing.T) {
+    checks := make(chan struct{}, 1)
+    old := checkRevocation
+    checkRevocation = func() error {
+        select {
+        case checks <- struct{}{}:
+        default:
+        }
+        return errors.Wrap(mtcareers.ErrTokenRevoked, "Revoked")
+    }
+    t.Cleanup(func() {
+        checkRevocation = old
+    })
+
+    stop := make(chan struct{})
+    done := make(chan struct{})
+    go func() {
+        watchToken(time.Millisecond, stop)
+        close(done)
+    } ()
+
+    for i := 0; i < 2; i++ {
+        select {
+        case <-checks:
+        case <-time.After(time.Second):
+            t.Fatalf("Expected the token to be checked periodically")
+        }
+    }
+    close(stop)
+    select {
+    case <-done:
+    case <-time.After(time.Second):
+        t.Fatalf("Expected the watcher to stop")
+    }
+}
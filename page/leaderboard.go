@@ -0,0 +1,247 @@
+package page
+
+import (
+    goErrors "errors"
+    "fmt"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "github.com/SirGFM/MTTitleCard/srlprofile"
+    "log"
+    "net/http"
+    "sort"
+    "strconv"
+)
+
+const (
+    // defaultPerPage is the number of players in each leaderboard page, if
+    // not requested otherwise
+    defaultPerPage = 20
+    // maxPerPage is the maximum number of players in a leaderboard page
+    maxPerPage = 100
+)
+
+// errUnknownMetric is returned when the leaderboard is sorted by an unknown
+// metric
+var errUnknownMetric = goErrors.New("Unknown leaderboard metric")
+
+// leaderboardMetric is a stat by which players may be ranked
+type leaderboardMetric struct {
+    // Name used to select the metric (e.g., "?sort=wins")
+    Name string
+    // Label describing the metric
+    Label string
+    // value of the stat for a given user
+    value func(u mtcareers.User) float32
+    // ascending is set if lower values rank higher
+    ascending bool
+}
+
+// leaderboardMetrics lists every stat by which players may be ranked. The
+// first one is used by default.
+var leaderboardMetrics = []leaderboardMetric {
+    {"wins", "Wins", func(u mtcareers.User) float32 { return float32(u.WinCount) }, false},
+    {"winrate", "Win Rate", winRate, false},
+    {"draft", "Draft Points", func(u mtcareers.User) float32 { return u.DraftPoints }, false},
+    {"tourneys", "MT Count", func(u mtcareers.User) float32 { return float32(u.TourneyCount) }, false},
+    {"placement", "Highest Placement", func(u mtcareers.User) float32 { return float32(u.HighestPosition) }, true},
+}
+
+// LeaderboardEntry is a ranked player in the leaderboard
+type LeaderboardEntry struct {
+    // Rank of the player. Tied players share the same rank.
+    Rank int
+    Data
+}
+
+// LeaderboardData maps a page of the leaderboard into an structure understood
+// by the leaderboard template page
+type LeaderboardData struct {
+    // Metric by which the players were ranked
+    Metric string
+    // MetricLabel describes the metric by which the players were ranked
+    MetricLabel string
+    // Metrics lists every metric by which players may be ranked
+    Metrics []leaderboardMetric
+    // Page is the current page, starting at 1
+    Page int
+    // Pages is the total number of pages
+    Pages int
+    // Prev and Next are the adjacent pages, or 0 if there's none
+    Prev int
+    Next int
+    // PerPage is the maximum number of players in each page
+    PerPage int
+    // Total number of ranked players
+    Total int
+    // Entries in the current page
+    Entries []LeaderboardEntry
+    ServiceUri string
+}
+
+// fetchAllUsers downloads every user in the MT Career spreadsheet. It may be
+// replaced on tests.
+var fetchAllUsers func() ([]mtcareers.User, error) = downloadAllUsers
+
+// cachedUsers retrieves every user in the MT Career spreadsheet, downloading
+// them only if they aren't cached (or if they have expired)
+func cachedUsers() ([]mtcareers.User, error) {
+    v, err := _allUsers.get(func() (interface{}, error) {
+        users, err := fetchAllUsers()
+        return users, err
+    })
+    if err != nil {
+        return nil, err
+    }
+    return v.([]mtcareers.User), nil
+}
+
+// downloadAllUsers downloads every user in the MT Career spreadsheet
+func downloadAllUsers() ([]mtcareers.User, error) {
+    sh, err := getProvider()
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to retrieve MT Career spreadsheet to generate the leaderboard")
+    }
+    err = sh.GetTourneyInfo()
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to get tourney info to generate the leaderboard")
+    }
+    users, err := sh.GetAllUsers()
+    // XXX: if err == nil, errors.Wrap returns nil as well!
+    return users, errors.Wrap(err, "Failed to get every MT Career user to generate the leaderboard")
+}
+
+// queryInt parses an integer query parameter, returning fallback if it's
+// missing or invalid
+func (r *request) queryInt(name string, fallback int) int {
+    val, err := strconv.Atoi(r.req.URL.Query().Get(name))
+    if err != nil {
+        return fallback
+    }
+    return val
+}
+
+// generateLeaderboard ranks every user by the requested metric, returning only
+// the requested page
+func generateLeaderboard(users []mtcareers.User, metric leaderboardMetric, page, perPage int) LeaderboardData {
+    ranked := make([]mtcareers.User, len(users))
+    copy(ranked, users)
+    sort.SliceStable(ranked, func(i, j int) bool {
+        if metric.ascending {
+            return metric.value(ranked[i]) < metric.value(ranked[j])
+        }
+        return metric.value(ranked[i]) > metric.value(ranked[j])
+    })
+
+    if perPage <= 0 || perPage > maxPerPage {
+        perPage = defaultPerPage
+    }
+    pages := (len(ranked) + perPage - 1) / perPage
+    if pages == 0 {
+        pages = 1
+    }
+    if page < 1 {
+        page = 1
+    } else if page > pages {
+        page = pages
+    }
+
+    data := LeaderboardData {
+        Metric: metric.Name,
+        MetricLabel: metric.Label,
+        Metrics: leaderboardMetrics,
+        Page: page,
+        Pages: pages,
+        PerPage: perPage,
+        Total: len(ranked),
+    }
+
+    if page > 1 {
+        data.Prev = page - 1
+    }
+    if page < pages {
+        data.Next = page + 1
+    }
+
+    // Tied players share the same rank (e.g., 1, 2, 2, 4)
+    rank := 0
+    first := (page - 1) * perPage
+    for i := 0; i < len(ranked) && i < first + perPage; i++ {
+        if i == 0 || metric.value(ranked[i]) != metric.value(ranked[i-1]) {
+            rank = i + 1
+        }
+        if i >= first {
+            data.Entries = append(data.Entries, LeaderboardEntry {
+                Rank: rank,
+                Data: generateDataFromUser(srlprofile.User{}, ranked[i]),
+            })
+        }
+    }
+
+    return data
+}
+
+// lookupLeaderboard generates the leaderboard page requested through the query
+// parameters "sort", "page" and "perPage"
+func (r *request) lookupLeaderboard() (LeaderboardData, error) {
+    name := r.req.URL.Query().Get("sort")
+    metric := leaderboardMetrics[0]
+    if name != "" {
+        found := false
+        for _, m := range leaderboardMetrics {
+            if m.Name == name {
+                metric = m
+                found = true
+                break
+            }
+        }
+        if !found {
+            return LeaderboardData{}, errors.Wrap(errUnknownMetric, fmt.Sprintf("Can't sort by '%s'", name))
+        }
+    }
+
+    users, err := cachedUsers()
+    if err != nil {
+        _upstreamErrors.record("leaderboard", err)
+        return LeaderboardData{}, err
+    }
+
+    data := generateLeaderboard(users, metric, r.queryInt("page", 1), r.queryInt("perPage", defaultPerPage))
+    data.ServiceUri = r.serviceUri
+    return data, nil
+}
+
+// getLeaderboard ranks every user, fit them into the leaderboard template and
+// return the resulting page
+func (r *request) getLeaderboard() {
+    data, err := r.lookupLeaderboard()
+    if err != nil {
+        serr := fmt.Sprintf("%+v", err)
+        if errors.Cause(err) == errUnknownMetric {
+            http.Error(r.w, serr, http.StatusBadRequest)
+        } else {
+            status, _ := errorStatus(err)
+            http.Error(r.w, serr, status)
+        }
+        log.Print(serr)
+        return
+    }
+
+    r.render(leaderboardPage, "text/html", data)
+}
+
+// getApiLeaderboard ranks every user, returning them as JSON
+func (r *request) getApiLeaderboard() {
+    data, err := r.lookupLeaderboard()
+    if err != nil {
+        log.Printf("%+v", err)
+        if errors.Cause(err) == errUnknownMetric {
+            r.apiFail(http.StatusBadRequest, err.Error())
+        } else {
+            status, _ := errorStatus(err)
+            r.apiFail(status, err.Error())
+        }
+        return
+    }
+
+    r.writeJson(http.StatusOK, data)
+}
@@ -0,0 +1,139 @@
+package page
+
+import (
+    "encoding/json"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+)
+
+// setupLeaderboard replaces the users downloaded from the MT Career
+// spreadsheet for the duration of the test
+func setupLeaderboard(t *testing.T, users []mtcareers.User) *int32 {
+    var count int32
+    old := fetchAllUsers
+    fetchAllUsers = func() ([]mtcareers.User, error) {
+        atomic.AddInt32(&count, 1)
+        return users, nil
+    }
+    _allUsers.invalidate()
+    t.Cleanup(func() {
+        fetchAllUsers = old
+        _allUsers.invalidate()
+    })
+    return &count
+}
+
+func TestLeaderboardRanks(t *testing.T) {
+    users := []mtcareers.User {
+        {Username: "A", WinCount: 3, HighestPosition: 2},
+        {Username: "B", WinCount: 7, HighestPosition: mtcareers.NoPlacement},
+        {Username: "C", WinCount: 3, HighestPosition: 1},
+        {Username: "D", WinCount: 1, HighestPosition: 2},
+    }
+
+    data := generateLeaderboard(users, leaderboardMetrics[0], 1, 10)
+    var got []string
+    var ranks []int
+    for _, e := range data.Entries {
+        got = append(got, e.Username)
+        ranks = append(ranks, e.Rank)
+    }
+    if strings.Join(got, ",") != "B,A,C,D" {
+        t.Errorf("Expected players sorted by wins, got %v", got)
+    }
+    if ranks[0] != 1 || ranks[1] != 2 || ranks[2] != 2 || ranks[3] != 4 {
+        t.Errorf("Expected tied players to share a rank, got %v", ranks)
+    }
+
+    var placement leaderboardMetric
+    for _, m := range leaderboardMetrics {
+        if m.Name == "placement" {
+            placement = m
+        }
+    }
+    data = generateLeaderboard(users, placement, 2, 2)
+    if data.Pages != 2 || data.Prev != 1 || data.Next != 0 {
+        t.Errorf("Unexpected pagination: %+v", data)
+    }
+    if len(data.Entries) != 2 || data.Entries[0].Username != "D" || data.Entries[0].Rank != 2 || data.Entries[1].Username != "B" {
+        t.Errorf("Expected the second page sorted by placement, got %+v", data.Entries)
+    }
+}
+
+func TestLeaderboardPage(t *testing.T) {
+    setupLeaderboard(t, []mtcareers.User {
+        {Username: "<b>Bold</b>", WinCount: 1, LoseCount: 1},
+        {Username: "GFM", WinCount: 2},
+    })
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/leaderboard?sort=winrate", nil))
+    body := w.Body.String()
+    if w.Code != 200 {
+        t.Fatalf("Expected 200, got %d: %s", w.Code, body)
+    }
+    if strings.Contains(body, "<b>Bold</b>") {
+        t.Errorf("Expected the username to be escaped")
+    }
+    if strings.Index(body, "GFM") > strings.Index(body, "Bold") {
+        t.Errorf("Expected GFM to rank first by win rate")
+    }
+
+    w = httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/leaderboard?sort=nope", nil))
+    if w.Code != 400 {
+        t.Errorf("Expected 400 for an unknown metric, got %d", w.Code)
+    }
+
+    w = httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/leaderboard?perPage=1&page=2", nil))
+    if w.Code != 200 {
+        t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+    }
+    var data LeaderboardData
+    err = json.Unmarshal(w.Body.Bytes(), &data)
+    if err != nil {
+        t.Fatalf("Failed to decode the leaderboard: %+v", err)
+    }
+    if data.Page != 2 || data.Pages != 2 || len(data.Entries) != 1 || data.Entries[0].Rank != 2 {
+        t.Errorf("Unexpected leaderboard page: %+v", data)
+    }
+}
+
+func TestLeaderboardIsCached(t *testing.T) {
+    setupCache(t, 0, nil)
+    count := setupLeaderboard(t, []mtcareers.User {
+        {Username: "GFM", WinCount: 2},
+    })
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    for i := 0; i < 3; i++ {
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/leaderboard", nil))
+        if w.Code != 200 {
+            t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+        }
+    }
+    if n := atomic.LoadInt32(count); n != 1 {
+        t.Errorf("Expected the spreadsheet to be downloaded once, got %d downloads", n)
+    }
+
+    PurgeAll()
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/leaderboard", nil))
+    if n := atomic.LoadInt32(count); n != 2 {
+        t.Errorf("Expected the spreadsheet to be downloaded again after a purge, got %d downloads", n)
+    }
+}
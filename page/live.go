@@ -0,0 +1,135 @@
+package page
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "net/url"
+    "strings"
+    "sync"
+)
+
+// liveTopic is used to publish every change to the players on air
+const liveTopic = "live"
+
+// liveState stores the players currently on air
+type liveState struct {
+    sync.Mutex
+    // players on air: either none, a single player or both players in a match
+    players []string
+}
+
+// LiveData is supplied to both the live and the producer pages
+type LiveData struct {
+    // Players currently on air
+    Players []string
+    // Path, relative to ServiceUri, of the card displaying the players on air
+    Path string
+    ServiceUri string
+}
+
+// liveEvent is published whenever the players on air change
+type liveEvent struct {
+    // Path, relative to the service, of the card displaying the players on
+    // air, or empty if nobody is on air
+    Path string
+}
+
+// livePath returns the path, relative to the service, of the card displaying
+// the supplied players
+func livePath(players []string) string {
+    switch len(players) {
+    case 1:
+        return "/" + url.PathEscape(players[0])
+    case 2:
+        return fmt.Sprintf("/vs/%s/%s", url.PathEscape(players[0]), url.PathEscape(players[1]))
+    default:
+        return ""
+    }
+}
+
+// get the players currently on air
+func (l *liveState) get() []string {
+    l.Lock()
+    defer l.Unlock()
+    return l.players
+}
+
+// set the players on air, switching every live page to them
+func (l *liveState) set(players []string) {
+    l.Lock()
+    l.players = players
+    l.Unlock()
+
+    log.Printf("Players on air: %+v", players)
+    _events.publish(liveTopic, "live", liveEvent {
+        Path: livePath(players),
+    })
+}
+
+// getLive returns the page displaying the players currently on air, or the
+// stream of changes to it
+func (r *request) getLive(path string) {
+    players := r.p.live.get()
+
+    switch path {
+    case "":
+        data := LiveData {
+            Players: players,
+            Path: livePath(players),
+            ServiceUri: r.serviceUri,
+        }
+        r.renderPage(r.p.livePage, "text/html", data)
+    case "events":
+        first, err := newEvent("live", liveEvent {
+            Path: livePath(players),
+        })
+        if err != nil {
+            log.Printf("%+v", err)
+        }
+        r.stream(liveTopic, &first, 0, nil)
+    default:
+        http.Error(r.w, "Unknown live resource", http.StatusNotFound)
+    }
+}
+
+// getProducer returns the page used to select the players on air. Just like
+// selecting them, it requires an admin.
+func (r *request) getProducer() {
+    if !r.checkAdmin() {
+        return
+    }
+
+    players := r.p.live.get()
+    data := LiveData {
+        Players: players,
+        Path: livePath(players),
+        ServiceUri: r.serviceUri,
+    }
+    r.renderPage(r.p.producerPage, "text/html", data)
+}
+
+// postProducer selects the players on air, as supplied in the form from the
+// producer page (as every POST, it requires an admin)
+func (r *request) postProducer() {
+    err := r.req.ParseForm()
+    if err != nil {
+        serr := fmt.Sprintf("%+v", err)
+        http.Error(r.w, serr, http.StatusBadRequest)
+        log.Print(serr)
+        return
+    }
+
+    var players []string
+    if r.req.PostFormValue("clear") == "" {
+        for _, field := range []string{"playerA", "playerB"} {
+            name := strings.TrimSpace(r.req.PostFormValue(field))
+            if name != "" {
+                players = append(players, name)
+            }
+        }
+    }
+    r.p.live.set(players)
+
+    http.Redirect(r.w, r.req, r.serviceUri + "/producer", http.StatusSeeOther)
+}
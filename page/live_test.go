@@ -0,0 +1,96 @@
+package page
+
+import (
+    "bufio"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "testing"
+)
+
+// nextData reads the event stream until the next event's data
+func nextData(t *testing.T, events *bufio.Reader) string {
+    for {
+        line, err := events.ReadString('\n')
+        if err != nil {
+            t.Fatalf("Failed to read the event stream: %+v", err)
+        } else if strings.HasPrefix(line, "data: ") {
+            return strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+        }
+    }
+}
+
+func TestLive(t *testing.T) {
+    setupCache(t, 0, nil)
+    setupAdmin(t)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+    ts := httptest.NewServer(ps)
+    defer ts.Close()
+
+    // Don't follow the redirect back to the producer page
+    client := ts.Client()
+    client.CheckRedirect = func(*http.Request, []*http.Request) error {
+        return http.ErrUseLastResponse
+    }
+
+    resp, err := client.Get(ts.URL + "/live/events")
+    if err != nil {
+        t.Fatalf("Failed to connect to the event stream: %+v", err)
+    }
+    defer resp.Body.Close()
+    events := bufio.NewReader(resp.Body)
+    if data := nextData(t, events); data != `{"Path":""}` {
+        t.Fatalf("Expected nobody on air, got %s", data)
+    }
+
+    for _, tc := range []struct {
+        form url.Values
+        path string
+    } {
+        {url.Values{"playerA": {"GFM"}}, "/GFM"},
+        {url.Values{"playerA": {"GFM"}, "playerB": {" Other "}}, "/vs/GFM/Other"},
+        {url.Values{"playerA": {"GFM"}, "clear": {"Clear"}}, ""},
+    } {
+        resp, err := postAsAdmin(client, ts.URL + "/producer", tc.form)
+        if err != nil {
+            t.Fatalf("Failed to select the players on air: %+v", err)
+        }
+        resp.Body.Close()
+        if resp.StatusCode != http.StatusSeeOther {
+            t.Fatalf("Expected a redirect to the producer page, got %d", resp.StatusCode)
+        }
+
+        expected := `{"Path":"` + tc.path + `"}`
+        if data := nextData(t, events); data != expected {
+            t.Fatalf("Expected %s, got %s", expected, data)
+        }
+    }
+
+    resp, err = postAsAdmin(client, ts.URL + "/producer", url.Values{"playerA": {"GFM"}})
+    if err == nil {
+        resp.Body.Close()
+    }
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/live", nil))
+    if !strings.Contains(w.Body.String(), `src="/GFM"`) {
+        t.Fatalf("Live page isn't displaying the player on air:\n%s", w.Body.String())
+    }
+
+    w = httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/producer", nil))
+    if w.Code != http.StatusUnauthorized {
+        t.Errorf("Expected the producer page to require an admin, got %d", w.Code)
+    }
+    req := httptest.NewRequest("GET", "/producer", nil)
+    req.SetBasicAuth("admin", "secret")
+    w = httptest.NewRecorder()
+    ps.ServeHTTP(w, req)
+    if w.Code != http.StatusOK {
+        t.Errorf("Expected the producer page to be served to admins, got %d", w.Code)
+    }
+}
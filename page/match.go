@@ -0,0 +1,93 @@
+package page
+
+import (
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "net/http"
+    "strings"
+)
+
+// MatchPlayer is one of the players in a match, alongside the stats on which
+// they lead
+type MatchPlayer struct {
+    Data
+    // LeadsWins is set if the player has more victories than their opponent
+    LeadsWins bool
+    // LeadsWinRate is set if the player has a better win rate than their
+    // opponent
+    LeadsWinRate bool
+    // LeadsDraftPoints is set if the player is worth more draft points than
+    // their opponent
+    LeadsDraftPoints bool
+    // LeadsPlacement is set if the player has placed higher than their
+    // opponent
+    LeadsPlacement bool
+}
+
+// MatchData maps both players in a match into an structure understood by the
+// match template page
+type MatchData struct {
+    Left MatchPlayer
+    Right MatchPlayer
+    ServiceUri string
+}
+
+// winRate calculates the rate of victories of a user, or -1 if the user
+// hasn't played yet
+func winRate(u mtcareers.User) float32 {
+    if u.WinCount + u.LoseCount == 0 {
+        return -1
+    }
+    return float32(u.WinCount) / float32(u.WinCount + u.LoseCount)
+}
+
+// generateMatchData compares both players, filling which one leads on each
+// stat. On ties, neither player leads.
+func generateMatchData(left, right Player) MatchData {
+    l := left.Career
+    r := right.Career
+    lRate := winRate(l)
+    rRate := winRate(r)
+
+    return MatchData {
+        Left: MatchPlayer {
+            Data: left.Data,
+            LeadsWins: l.WinCount > r.WinCount,
+            LeadsWinRate: lRate > rRate,
+            LeadsDraftPoints: l.DraftPoints > r.DraftPoints,
+            LeadsPlacement: l.HighestPosition < r.HighestPosition,
+        },
+        Right: MatchPlayer {
+            Data: right.Data,
+            LeadsWins: r.WinCount > l.WinCount,
+            LeadsWinRate: rRate > lRate,
+            LeadsDraftPoints: r.DraftPoints > l.DraftPoints,
+            LeadsPlacement: r.HighestPosition < l.HighestPosition,
+        },
+    }
+}
+
+// getMatch parses both players in a match, fit them into the match template
+// and return the resulting page. players must be formatted as "playerA/playerB".
+func (r *request) getMatch(players string) {
+    usernames := strings.Split(players, "/")
+    if len(usernames) != 2 || usernames[0] == "" || usernames[1] == "" {
+        http.Error(r.w, "Expected two players: /vs/{playerA}/{playerB}", http.StatusNotFound)
+        return
+    }
+
+    // Players that fail are replaced by a placeholder, but the status code
+    // still reports the first failure
+    left, lerr := lookupPlayer(usernames[0])
+    right, rerr := lookupPlayer(usernames[1])
+    status := http.StatusOK
+    if lerr != nil {
+        status, _ = errorStatus(lerr)
+    } else if rerr != nil {
+        status, _ = errorStatus(rerr)
+    }
+
+    data := generateMatchData(left, right)
+    data.ServiceUri = r.serviceUri
+
+    r.renderStatus(matchPage, "text/html", status, data)
+}
@@ -0,0 +1,73 @@
+package page
+
+import (
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestMatchLeads(t *testing.T) {
+    var left, right Player
+
+    left.Career = mtcareers.User {
+        WinCount: 10,
+        LoseCount: 10,
+        DraftPoints: 5,
+        HighestPosition: 3,
+    }
+    right.Career = mtcareers.User {
+        WinCount: 6,
+        LoseCount: 2,
+        DraftPoints: 5,
+        HighestPosition: mtcareers.NoPlacement,
+    }
+
+    data := generateMatchData(left, right)
+    if !data.Left.LeadsWins || data.Right.LeadsWins {
+        t.Errorf("Expected left to lead on wins")
+    }
+    if data.Left.LeadsWinRate || !data.Right.LeadsWinRate {
+        t.Errorf("Expected right to lead on win rate")
+    }
+    if data.Left.LeadsDraftPoints || data.Right.LeadsDraftPoints {
+        t.Errorf("Expected neither player to lead on draft points")
+    }
+    if !data.Left.LeadsPlacement || data.Right.LeadsPlacement {
+        t.Errorf("Expected left to lead on placement")
+    }
+}
+
+func TestMatchPage(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        var p Player
+        p.Data = generateDataFromUser(p.Srl, mtcareers.User {
+            Username: username,
+            WinCount: len(username),
+        })
+        p.Career.WinCount = len(username)
+        return p, nil
+    })
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/vs/GFM/%3Ci%3ELong", nil))
+    body := w.Body.String()
+    if w.Code != 200 {
+        t.Fatalf("Expected status 200, got %d", w.Code)
+    } else if !strings.Contains(body, "GFM") || !strings.Contains(body, "&lt;i&gt;Long") {
+        t.Fatalf("Missing a player in the page:\n%s", body)
+    } else if strings.Count(body, " lead\"") != 1 {
+        t.Fatalf("Expected a single stat to be highlighted:\n%s", body)
+    }
+
+    w = httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/vs/GFM", nil))
+    if w.Code != 404 {
+        t.Fatalf("Expected status 404 for a single player, got %d", w.Code)
+    }
+}
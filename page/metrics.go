@@ -0,0 +1,150 @@
+package page
+
+import (
+    "github.com/SirGFM/MTTitleCard/metrics"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "net/http"
+    "path/filepath"
+    "strconv"
+    "time"
+)
+
+var (
+    httpRequests = metrics.Default.NewCounter("mttc_http_requests_total",
+            "Requests handled, by route, method and status code.", "route", "method", "code")
+    httpDuration = metrics.Default.NewHistogram("mttc_http_request_duration_seconds",
+            "Latency of handled requests, by route. Event streams aren't included.", metrics.DefaultBuckets, "route")
+    streamDuration = metrics.Default.NewHistogram("mttc_http_stream_duration_seconds",
+            "How long event streams stayed connected, by route.", streamBuckets, "route")
+    cacheHits = metrics.Default.NewCounter("mttc_player_cache_hits_total",
+            "Players served from the cache, either fresh or stale.", "state")
+    cacheMisses = metrics.Default.NewCounter("mttc_player_cache_misses_total",
+            "Players that had to be downloaded before being served.")
+)
+
+// streamBuckets are the histogram buckets for how long event streams stayed
+// connected, from a few seconds to a whole day
+var streamBuckets = []float64{1, 10, 60, 300, 900, 1800, 3600, 7200, 14400, 43200, 86400}
+
+func init() {
+    metrics.Default.NewGaugeFunc("mttc_oauth_token_age_seconds",
+            "Time since the OAuth token was last saved.", func() (float64, bool) {
+        age, err := mtcareers.TokenAge()
+        return age.Seconds(), err == nil
+    })
+}
+
+// routes lists every path, besides the players, handled by the server
+var routes = map[string]bool {
+    "style.css": true,
+    "favicon.ico": true,
+    "api": true,
+    "vs": true,
+    "assets": true,
+    "events": true,
+    "live": true,
+    "producer": true,
+    "leaderboard": true,
+    "tournament": true,
+    "admin": true,
+    "healthz": true,
+    "readyz": true,
+    "metrics": true,
+    "oauth": true,
+    "refresh": true,
+    "theme": true,
+}
+
+// playerFormats lists the extensions of the players reported as their own
+// route (e.g., "player.png")
+var playerFormats = map[string]bool {
+    ".png": true,
+    ".svg": true,
+    ".json": true,
+}
+
+// methods lists every method reported in metrics. Any other method is reported
+// as "other".
+var methods = map[string]bool {
+    "GET": true,
+    "POST": true,
+    "DELETE": true,
+}
+
+// routeName maps the request to the route reported in metrics, so neither
+// usernames nor arbitrary extensions end up as labels
+func (r *request) routeName() string {
+    base, _ := splitPath(r.path)
+    switch {
+    case base == "", base == "index", base == "index.html":
+        return "index"
+    case routes[base]:
+        return base
+    }
+    if ext := filepath.Ext(r.path); playerFormats[ext] {
+        return "player" + ext
+    }
+    return "player"
+}
+
+// methodName maps the request's method to the method reported in metrics
+func (r *request) methodName() string {
+    if methods[r.req.Method] {
+        return r.req.Method
+    }
+    return "other"
+}
+
+// statusWriter records the status code sent to the client
+type statusWriter struct {
+    http.ResponseWriter
+    status int
+}
+
+// WriteHeader records the status code before sending it
+func (w *statusWriter) WriteHeader(status int) {
+    if w.status == 0 {
+        w.status = status
+    }
+    w.ResponseWriter.WriteHeader(status)
+}
+
+// Write sends data to the client, implicitly sending 200 OK if no status was
+// sent yet
+func (w *statusWriter) Write(data []byte) (int, error) {
+    if w.status == 0 {
+        w.status = http.StatusOK
+    }
+    return w.ResponseWriter.Write(data)
+}
+
+// Flush forwards buffered data to the client, as used by event streams
+func (w *statusWriter) Flush() {
+    if f, ok := w.ResponseWriter.(http.Flusher); ok {
+        f.Flush()
+    }
+}
+
+// observe records a handled request, started at start. Event streams last for
+// as long as the client stays connected, so they are recorded separately from
+// the latency of every other request.
+func (r *request) observe(w *statusWriter, start time.Time) {
+    status := w.status
+    if status == 0 {
+        status = http.StatusOK
+    }
+    route := r.routeName()
+    httpRequests.Inc(route, r.methodName(), strconv.Itoa(status))
+    if w.Header().Get("Content-Type") == "text/event-stream" {
+        streamDuration.Observe(time.Since(start).Seconds(), route)
+    } else {
+        httpDuration.Observe(time.Since(start).Seconds(), route)
+    }
+}
+
+// getMetrics returns every metric in Prometheus' text format
+func (r *request) getMetrics() {
+    r.w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+    r.w.WriteHeader(http.StatusOK)
+    metrics.Default.WriteText(r.w)
+}
@@ -0,0 +1,133 @@
+package page
+
+import (
+    "bufio"
+    "context"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+// scrapeMetrics retrieves every sample from the server's metrics endpoint
+func scrapeMetrics(t *testing.T, ps PageServer) map[string]float64 {
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+    if w.Code != 200 {
+        t.Fatalf("Expected 200, got %d", w.Code)
+    }
+
+    samples := map[string]float64{}
+    scanner := bufio.NewScanner(w.Body)
+    for scanner.Scan() {
+        line := scanner.Text()
+        if strings.HasPrefix(line, "#") {
+            continue
+        }
+        idx := strings.LastIndexByte(line, ' ')
+        val, err := strconv.ParseFloat(line[idx+1:], 64)
+        if err != nil {
+            t.Fatalf("Invalid sample '%s': %+v", line, err)
+        }
+        samples[line[:idx]] = val
+    }
+    return samples
+}
+
+func TestMetrics(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        var p Player
+        p.Data.Username = username
+        return p, nil
+    })
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    before := scrapeMetrics(t, ps)
+    for _, path := range []string{"/GFM", "/GFM", "/Other.svg", "/vs/GFM/Other", "/healthz"} {
+        ps.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+    }
+    after := scrapeMetrics(t, ps)
+
+    for sample, diff := range map[string]float64 {
+        `mttc_http_requests_total{route="player",method="GET",code="200"}`: 2,
+        `mttc_http_requests_total{route="player.svg",method="GET",code="200"}`: 1,
+        `mttc_http_requests_total{route="vs",method="GET",code="200"}`: 1,
+        `mttc_http_requests_total{route="healthz",method="GET",code="200"}`: 1,
+        `mttc_http_request_duration_seconds_count{route="player"}`: 2,
+        `mttc_player_cache_misses_total`: 2,
+        `mttc_player_cache_hits_total{state="fresh"}`: 3,
+    } {
+        if got := after[sample] - before[sample]; got != diff {
+            t.Errorf("Expected %s to increase by %v, got %v", sample, diff, got)
+        }
+    }
+    for sample := range after {
+        if strings.Contains(sample, "GFM") {
+            t.Errorf("Usernames shouldn't be used as labels: %s", sample)
+        }
+    }
+}
+
+func TestMetricsBoundedLabels(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        var p Player
+        p.Data.Username = username
+        return p, nil
+    })
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    before := scrapeMetrics(t, ps)
+    for _, path := range []string{"/x.a1", "/x.a2"} {
+        ps.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+    }
+    ps.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/", nil))
+    after := scrapeMetrics(t, ps)
+
+    for sample, diff := range map[string]float64 {
+        `mttc_http_requests_total{route="player",method="GET",code="200"}`: 2,
+        `mttc_http_requests_total{route="index",method="other",code="405"}`: 1,
+    } {
+        if got := after[sample] - before[sample]; got != diff {
+            t.Errorf("Expected %s to increase by %v, got %v", sample, diff, got)
+        }
+    }
+    for sample := range after {
+        if strings.Contains(sample, "a1") || strings.Contains(sample, "BREW") {
+            t.Errorf("Client supplied values shouldn't be used as labels: %s", sample)
+        }
+    }
+}
+
+func TestMetricsStreams(t *testing.T) {
+    setupCache(t, 0, nil)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    before := scrapeMetrics(t, ps)
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    req := httptest.NewRequest("GET", "/events/GFM", nil).WithContext(ctx)
+    ps.ServeHTTP(httptest.NewRecorder(), req)
+    after := scrapeMetrics(t, ps)
+
+    for sample, diff := range map[string]float64 {
+        `mttc_http_requests_total{route="events",method="GET",code="200"}`: 1,
+        `mttc_http_stream_duration_seconds_count{route="events"}`: 1,
+        `mttc_http_request_duration_seconds_count{route="events"}`: 0,
+    } {
+        if got := after[sample] - before[sample]; got != diff {
+            t.Errorf("Expected %s to increase by %v, got %v", sample, diff, got)
+        }
+    }
+}
@@ -24,8 +24,16 @@ type Data struct {
     ServiceUri string
 }
 
-// _cache of already downloaded and parsed users
-var _cache map[string]Data = map[string]Data{}
+// Player stores every information retrieved for a user
+type Player struct {
+    // Data is the merged information displayed in the title card
+    Data Data `json:"data"`
+    // Srl is the user as retrieved from SRL
+    Srl srlprofile.User `json:"srl"`
+    // Career is the user as retrieved from the MT Career spreadsheet
+    Career mtcareers.User `json:"career"`
+}
+
 // _fmtNumber maps the unit of a position to its suffix
 var _fmtNumber map[int]string = map[int]string {
     1: "%dst",
@@ -36,31 +44,59 @@ var _fmtNumber map[int]string = map[int]string {
 // GenerateData downloads, parses and caches data for a given username.
 // srlUsername and username should be the same.
 func GenerateData(srlUsername, username string) error {
-    if _, ok := _cache[username]; ok {
-        // User already parsed and cached
-        return nil
+    _, err := getPlayer(srlUsername, username)
+    return err
+}
+
+// getPlayer retrieves a user from the cache, downloading it if needed. Expired
+// users are served as is, while they are refreshed in the background.
+// Concurrent look ups for the same user share a single download.
+func getPlayer(srlUsername, username string) (Player, error) {
+    p, state := _cache.get(username)
+    switch state {
+    case cacheFresh:
+        cacheHits.Inc("fresh")
+        return p, nil
+    case cacheStale:
+        cacheHits.Inc("stale")
+        _cache.revalidate(srlUsername, username)
+        return p, nil
     }
+    cacheMisses.Inc()
+
+    p, _, err := _cache.fetch(srlUsername, username)
+    return p, err
+}
+
+// getProvider retrieves the source of MT Career data. It may be replaced on
+// tests.
+var getProvider func() (mtcareers.Provider, error) = mtcareers.GetProvider
 
+// fetchPlayer downloads and parses the data for a given username.
+func fetchPlayer(srlUsername, username string) (Player, error) {
     srlUser, err := srlprofile.GetFromUsername(srlUsername)
     if err != nil {
-        return errors.Wrap(err, "Failed to get SRL Profile to generate user data")
+        return Player{}, errors.Wrap(err, "Failed to get SRL Profile to generate user data")
     }
 
-    sh, err := mtcareers.GetSheet()
+    sh, err := getProvider()
     if err != nil {
-        return errors.Wrap(err, "Failed to retrieve MT Career spreadsheet to generate user data")
+        return Player{}, errors.Wrap(err, "Failed to retrieve MT Career spreadsheet to generate user data")
     }
     err = sh.GetTourneyInfo()
     if err != nil {
-        return errors.Wrap(err, "Failed to get tourney info to generate user data")
+        return Player{}, errors.Wrap(err, "Failed to get tourney info to generate user data")
     }
     mtUser, err := sh.GetUserInfo(username)
     if err != nil {
-        return errors.Wrap(err, "Failed to get MT Career user info to generate user data")
+        return Player{}, errors.Wrap(err, "Failed to get MT Career user info to generate user data")
     }
-    _cache[username] = generateDataFromUser(srlUser, mtUser)
 
-    return nil
+    return Player {
+        Data: generateDataFromUser(srlUser, mtUser),
+        Srl: srlUser,
+        Career: mtUser,
+    }, nil
 }
 
 // generateDataFromUser merges the SRL User and the MT Career User in a single
@@ -90,7 +126,9 @@ func generateDataFromUser(srlUser srlprofile.User, mtUser mtcareers.User) Data {
         Username: mtUser.Username,
         Avatar: srlUser.SrlAvatar,
         Joined: mtUser.FirstMT,
-        MtCount: mtUser.TourneyCount,
+        // XXX: The careers was last updated for MT14,
+        //      so MtCount is wrong... :grimacing:
+        MtCount: mtUser.TourneyCount + 1,
         Wins: mtUser.WinCount,
         Losses: mtUser.LoseCount,
         WinRate: rateStr,
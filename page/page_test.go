@@ -1,6 +1,7 @@
 package page
 
 import (
+    "github.com/pkg/errors"
     "github.com/SirGFM/MTTitleCard/mtcareers"
     "github.com/SirGFM/MTTitleCard/srlprofile"
     "testing"
@@ -155,3 +156,82 @@ func TestPlacement(t *testing.T) {
         }
     }
 }
+
+// fakeProvider serves a fixed set of users, failing if the tourney info
+// wasn't loaded first
+type fakeProvider struct {
+    users []mtcareers.User
+    tournament mtcareers.Tournament
+    loaded bool
+}
+
+func (p *fakeProvider) GetTourneyInfo() error {
+    p.loaded = true
+    return nil
+}
+
+func (p *fakeProvider) GetUserInfo(username string) (mtcareers.User, error) {
+    if !p.loaded {
+        return mtcareers.User{}, errors.New("Tourney info wasn't loaded")
+    }
+    for _, u := range p.users {
+        if u.Username == username {
+            return u, nil
+        }
+    }
+    return mtcareers.User{}, errors.Wrap(mtcareers.ErrUserNotFound, username)
+}
+
+func (p *fakeProvider) GetAllUsers() ([]mtcareers.User, error) {
+    if !p.loaded {
+        return nil, errors.New("Tourney info wasn't loaded")
+    }
+    return p.users, nil
+}
+
+func (p *fakeProvider) GetTournament() (mtcareers.Tournament, error) {
+    if !p.loaded {
+        return mtcareers.Tournament{}, errors.New("Tourney info wasn't loaded")
+    }
+    return p.tournament, nil
+}
+
+// setupProvider replaces the source of MT Career data by a fake one
+func setupProvider(t *testing.T, users []mtcareers.User, tournament mtcareers.Tournament) {
+    oldProvider := getProvider
+    getProvider = func() (mtcareers.Provider, error) {
+        return &fakeProvider {
+            users: users,
+            tournament: tournament,
+        }, nil
+    }
+    t.Cleanup(func() {
+        getProvider = oldProvider
+    })
+}
+
+func TestFakeProvider(t *testing.T) {
+    setupProvider(t, []mtcareers.User {
+        {Username: "Alice", TourneyCount: 3},
+        {Username: "Bob", TourneyCount: 1},
+    }, mtcareers.Tournament {
+        LatestEntrants: 2,
+    })
+
+    users, err := downloadAllUsers()
+    if err != nil || len(users) != 2 || users[0].Username != "Alice" {
+        t.Errorf("Expected the fake users, got %+v (%+v)", users, err)
+    }
+
+    tour, err := downloadTournament()
+    if err != nil || tour.LatestEntrants != 2 {
+        t.Errorf("Expected the fake tournament, got %+v (%+v)", tour, err)
+    }
+
+    sh, _ := getProvider()
+    sh.GetTourneyInfo()
+    _, err = sh.GetUserInfo("Carol")
+    if status, _ := errorStatus(err); status != 404 {
+        t.Errorf("Expected a missing user to be reported as 404, got %d (%+v)", status, err)
+    }
+}
@@ -0,0 +1,287 @@
+package page
+
+import (
+    "bytes"
+    "fmt"
+    "github.com/pkg/errors"
+    "golang.org/x/image/draw"
+    "golang.org/x/image/font"
+    "golang.org/x/image/font/gofont/gobold"
+    "golang.org/x/image/font/opentype"
+    "golang.org/x/image/math/fixed"
+    "image"
+    "image/color"
+    _ "image/gif"
+    _ "image/jpeg"
+    "image/png"
+    "io"
+    "io/ioutil"
+    "log"
+    "net/http"
+    "sync"
+    "time"
+)
+
+// Dimensions, in pixels, of the PNG title card
+const (
+    pngWidth = 480
+    pngMargin = 24
+    pngAvatarSize = 100
+    pngRowHeight = 28
+    // pngLabelWidth is the width of the stats' label, as in the CSS
+    pngLabelWidth = (pngWidth - 2 * pngMargin) * 70 / 100
+)
+
+// Limits of the downloaded avatars, so a huge image can't exhaust the memory
+const (
+    // maxAvatarSize is the maximum size, in bytes, of the avatar's file
+    maxAvatarSize = 2 << 20
+    // maxAvatarPixels is the maximum number of pixels in the decoded avatar
+    maxAvatarPixels = 4096 * 4096
+)
+
+// Sizes, in points, of the fonts in the PNG title card. These roughly match
+// the default CSS (small, xx-large and medium, respectively).
+const (
+    pngChannelSize = 13
+    pngUsernameSize = 32
+    pngStatsSize = 16
+)
+
+// Colors of the PNG title card, taken from the default style
+var (
+    pngBackground color.Color = color.RGBA{0x20, 0x22, 0x25, 0xff}
+    pngForeground color.Color = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// pngFont stores the font used in the PNG title card, parsed only once
+var pngFont struct {
+    sync.Once
+    font *opentype.Font
+    err error
+}
+
+// cardFaces are the font faces used to draw a single PNG title card. Faces
+// aren't safe for concurrent use, so each render creates its own.
+type cardFaces struct {
+    channel font.Face
+    username font.Face
+    stats font.Face
+}
+
+// avatarClient is used to download avatars, so a slow server doesn't block
+// the title card for too long
+var avatarClient *http.Client = &http.Client {
+    Timeout: 5 * time.Second,
+}
+
+// loadFont parses the embedded font, only once
+func loadFont() (*opentype.Font, error) {
+    pngFont.Do(func() {
+        pngFont.font, pngFont.err = opentype.Parse(gobold.TTF)
+        // XXX: if err == nil, errors.Wrap returns nil as well!
+        pngFont.err = errors.Wrap(pngFont.err, "Failed to parse the embedded font")
+    })
+    return pngFont.font, pngFont.err
+}
+
+// newFaces creates the embedded font's faces in every size used by the PNG
+// title card
+func newFaces() (cardFaces, error) {
+    var faces cardFaces
+
+    f, err := loadFont()
+    if err != nil {
+        return faces, err
+    }
+
+    for _, face := range []struct {
+        size float64
+        dst *font.Face
+    } {
+        {pngChannelSize, &faces.channel},
+        {pngUsernameSize, &faces.username},
+        {pngStatsSize, &faces.stats},
+    } {
+        *face.dst, err = opentype.NewFace(f, &opentype.FaceOptions {
+            Size: face.size,
+            DPI: 72,
+            Hinting: font.HintingFull,
+        })
+        if err != nil {
+            return faces, errors.Wrap(err, "Failed to create a font face")
+        }
+    }
+    return faces, nil
+}
+
+// downloadAvatar retrieves and decodes the image in url, scaling it to the
+// size of the avatar in the PNG title card
+func downloadAvatar(url string) (image.Image, error) {
+    resp, err := avatarClient.Get(url)
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to download the avatar")
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, errors.New(fmt.Sprintf("Failed to download the avatar: %s", resp.Status))
+    }
+    data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxAvatarSize + 1))
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to download the avatar")
+    } else if len(data) > maxAvatarSize {
+        return nil, errors.Errorf("Failed to download the avatar: larger than %d bytes", maxAvatarSize)
+    }
+
+    cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to decode the avatar")
+    } else if cfg.Width * cfg.Height > maxAvatarPixels {
+        return nil, errors.Errorf("Failed to decode the avatar: %dx%d is too large", cfg.Width, cfg.Height)
+    }
+    img, _, err := image.Decode(bytes.NewReader(data))
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to decode the avatar")
+    }
+
+    scaled := image.NewRGBA(image.Rect(0, 0, pngAvatarSize, pngAvatarSize))
+    draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+    return scaled, nil
+}
+
+// getAvatar retrieves a player's avatar, downloading it only once per cached
+// player (even if it fails) unless the avatar changes. Returns nil if the
+// avatar couldn't be retrieved.
+func (c *playerCache) getAvatar(username, url string) image.Image {
+    key := cacheKey(username)
+
+    c.Lock()
+    if e, ok := c.entries[key]; ok && e.avatarUrl == url {
+        img := e.avatar
+        c.Unlock()
+        return img
+    }
+    c.Unlock()
+
+    img, err := downloadAvatar(url)
+    if err != nil {
+        // XXX: Failing to get the avatar isn't (imo) a critical error...
+        log.Printf("Failed to get the player's avatar: %+v", err)
+    }
+
+    c.Lock()
+    if e, ok := c.entries[key]; ok && e.player.Data.Avatar == url {
+        e.avatar = img
+        e.avatarUrl = url
+    }
+    c.Unlock()
+    return img
+}
+
+// cardStats lists every label and value displayed in the title card, as in
+// the default template
+func cardStats(data Data) [][2]string {
+    losses := fmt.Sprintf("%d", data.Losses)
+    if data.Losses == 0 {
+        losses += " (Flawless!)"
+    }
+    placement := data.HighestPlacement
+    if placement == "9999th" {
+        placement = "N/A"
+    }
+
+    return [][2]string {
+        {"Joined", data.Joined},
+        {"MT Count", fmt.Sprintf("%d", data.MtCount)},
+        {"Wins", fmt.Sprintf("%d", data.Wins)},
+        {"Losses", losses},
+        {"Win Rate", data.WinRate + "%"},
+        {"Draft Points", fmt.Sprintf("%d", data.DraftPoints)},
+        {"Highest Placement", placement},
+    }
+}
+
+// drawText writes text with its baseline starting at (x, y)
+func drawText(dst draw.Image, face font.Face, x, y int, text string) {
+    d := font.Drawer {
+        Dst: dst,
+        Src: image.NewUniform(pngForeground),
+        Face: face,
+        Dot: fixed.P(x, y),
+    }
+    d.DrawString(text)
+}
+
+// renderPng draws the title card into w. avatar may be nil, in which case it's
+// simply skipped.
+func renderPng(w io.Writer, data Data, avatar image.Image) error {
+    faces, err := newFaces()
+    if err != nil {
+        return err
+    }
+
+    stats := cardStats(data)
+    channelTop := pngMargin / 2
+    userTop := channelTop + pngChannelSize + pngMargin
+    statsTop := userTop + pngAvatarSize + pngMargin
+    height := statsTop + len(stats) * pngRowHeight + pngMargin
+
+    img := image.NewRGBA(image.Rect(0, 0, pngWidth, height))
+    draw.Draw(img, img.Bounds(), image.NewUniform(pngBackground), image.Point{}, draw.Src)
+
+    // Channel, aligned to the right
+    channel := "twitch.tv/" + data.Channel
+    width := font.MeasureString(faces.channel, channel).Round()
+    drawText(img, faces.channel, pngWidth - pngMargin - width,
+            channelTop + pngChannelSize, channel)
+
+    // Avatar and username, vertically centered with the avatar
+    nameLeft := pngMargin
+    if avatar != nil {
+        dst := image.Rect(pngMargin, userTop, pngMargin + pngAvatarSize,
+                userTop + pngAvatarSize)
+        draw.CatmullRom.Scale(img, dst, avatar, avatar.Bounds(), draw.Over, nil)
+        nameLeft += pngAvatarSize + pngMargin / 2
+    }
+    drawText(img, faces.username, nameLeft,
+            userTop + (pngAvatarSize + pngUsernameSize * 3 / 4) / 2, data.Username)
+
+    // Stats table
+    for i, row := range stats {
+        y := statsTop + i * pngRowHeight + pngStatsSize
+        drawText(img, faces.stats, pngMargin, y, row[0])
+        drawText(img, faces.stats, pngMargin + pngLabelWidth, y, row[1])
+    }
+
+    // XXX: if err == nil, errors.Wrap returns nil as well!
+    return errors.Wrap(png.Encode(w, img), "Failed to encode the PNG")
+}
+
+// getUserPng parse username info and draw it into a PNG image
+func (r *request) getUserPng(username string) {
+    p, err := lookupPlayer(username)
+    status := http.StatusOK
+    if err != nil {
+        status, _ = errorStatus(err)
+    }
+    data := p.Data
+
+    var avatar image.Image
+    if data.Avatar != "" {
+        avatar = _cache.getAvatar(username, data.Avatar)
+    }
+
+    var buf bytes.Buffer
+    err = renderPng(&buf, data, avatar)
+    if err != nil {
+        serr := fmt.Sprintf("%+v", err)
+        http.Error(r.w, serr, http.StatusInternalServerError)
+        log.Print(serr)
+        return
+    }
+
+    r.w.Header().Set("Content-Type", "image/png")
+    r.w.WriteHeader(status)
+    r.w.Write(buf.Bytes())
+}
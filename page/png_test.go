@@ -0,0 +1,140 @@
+package page
+
+import (
+    "bytes"
+    "encoding/binary"
+    "fmt"
+    "hash/crc32"
+    "image"
+    "image/png"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+)
+
+func TestRenderPng(t *testing.T) {
+    var buf bytes.Buffer
+
+    data := Data {
+        Channel: "gfm",
+        Username: "GFM",
+        Joined: "MT1",
+        WinRate: "N/A",
+        HighestPlacement: "9999th",
+    }
+    err := renderPng(&buf, data, nil)
+    if err != nil {
+        t.Fatalf("Failed to render the PNG: %+v", err)
+    }
+
+    img, err := png.Decode(&buf)
+    if err != nil {
+        t.Fatalf("Failed to decode the rendered PNG: %+v", err)
+    } else if img.Bounds().Dx() != pngWidth {
+        t.Fatalf("Expected a %dpx wide image, got %dpx", pngWidth, img.Bounds().Dx())
+    }
+
+    r, g, b, _ := img.At(0, 0).RGBA()
+    er, eg, eb, _ := pngBackground.RGBA()
+    if r != er || g != eg || b != eb {
+        t.Fatalf("Expected the background to use the default style's color")
+    }
+}
+
+func TestConcurrentRenderPng(t *testing.T) {
+    var wg sync.WaitGroup
+    errs := make(chan error, 16)
+    for i := 0; i < 16; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            var buf bytes.Buffer
+            data := Data {
+                Channel: "gfm",
+                Username: fmt.Sprintf("Player %d", i),
+                Joined: "MT1",
+                WinRate: "50",
+                HighestPlacement: "1st",
+            }
+            errs <- renderPng(&buf, data, nil)
+        } (i)
+    }
+    wg.Wait()
+    close(errs)
+
+    for err := range errs {
+        if err != nil {
+            t.Errorf("Failed to render the PNG: %+v", err)
+        }
+    }
+}
+
+// servePng serves a PNG of the supplied size, counting every download. Its
+// header may claim a different size, so huge images aren't actually encoded.
+func servePng(t *testing.T, size, claimedSize int, count *int32) *httptest.Server {
+    var buf bytes.Buffer
+    err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, size, size)))
+    if err != nil {
+        t.Fatalf("Failed to encode the avatar: %+v", err)
+    }
+
+    // The IHDR chunk starts right after the signature: its length, type,
+    // width, height, the rest of its data and its CRC
+    data := buf.Bytes()
+    binary.BigEndian.PutUint32(data[16:], uint32(claimedSize))
+    binary.BigEndian.PutUint32(data[20:], uint32(claimedSize))
+    binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+    ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+        atomic.AddInt32(count, 1)
+        w.Header().Set("Content-Type", "image/png")
+        w.Write(data)
+    }))
+    t.Cleanup(ts.Close)
+    return ts
+}
+
+func TestAvatarIsCached(t *testing.T) {
+    var count int32
+    ts := servePng(t, 200, 200, &count)
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        return Player{Data: Data{Username: username, Avatar: ts.URL}}, nil
+    })
+
+    _, err := getPlayer("GFM", "GFM")
+    if err != nil {
+        t.Fatalf("Failed to get user: %+v", err)
+    }
+    for i := 0; i < 3; i++ {
+        img := _cache.getAvatar("gfm", ts.URL)
+        if img == nil || img.Bounds().Dx() != pngAvatarSize {
+            t.Fatalf("Expected the avatar to be scaled to %dpx: %+v", pngAvatarSize, img)
+        }
+    }
+    if n := atomic.LoadInt32(&count); n != 1 {
+        t.Errorf("Expected the avatar to be downloaded once, got %d downloads", n)
+    }
+
+    // Downloading the player again keeps the avatar
+    _, _, err = _cache.fetch("GFM", "GFM")
+    if err != nil {
+        t.Fatalf("Failed to get user: %+v", err)
+    }
+    _cache.getAvatar("GFM", ts.URL)
+    if n := atomic.LoadInt32(&count); n != 1 {
+        t.Errorf("Expected the avatar to be kept, got %d downloads", n)
+    }
+}
+
+func TestAvatarTooLarge(t *testing.T) {
+    var count int32
+    ts := servePng(t, 1, 5000, &count)
+
+    _, err := downloadAvatar(ts.URL)
+    if err == nil || !strings.Contains(err.Error(), "too large") {
+        t.Errorf("Expected the avatar to be rejected, got %+v", err)
+    }
+}
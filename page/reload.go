@@ -0,0 +1,131 @@
+package page
+
+import (
+    "github.com/SirGFM/MTTitleCard/config"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "log"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// themeModTimes maps every file (and directory) within dir to when it was last
+// modified
+func themeModTimes(dir string) map[string]time.Time {
+    times := map[string]time.Time{}
+    if dir == "" {
+        return times
+    }
+
+    filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+        if err == nil {
+            times[path] = info.ModTime()
+        }
+        // Keep walking even if a single file fails
+        return nil
+    })
+    return times
+}
+
+// sameModTimes checks whether both lists of files are exactly the same
+func sameModTimes(a, b map[string]time.Time) bool {
+    if len(a) != len(b) {
+        return false
+    }
+    for path, t := range a {
+        if other, ok := b[path]; !ok || !other.Equal(t) {
+            return false
+        }
+    }
+    return true
+}
+
+// reloadThemes checks whether any CSS or template file got modified and, if
+// so, parses every theme once again. Themes that fail to parse keep being
+// served as they were before the modification.
+func (p *pageServer) reloadThemes() {
+    configChanged, err := config.ReloadFiles()
+    if err != nil {
+        log.Printf("Failed to reload the custom files, keeping the last ones: %+v", err)
+    }
+
+    files := themeModTimes(config.Get().ThemesDir)
+    p.themeLock.RLock()
+    themesChanged := !sameModTimes(files, p.themeFiles)
+    p.themeLock.RUnlock()
+
+    if !configChanged && !themesChanged {
+        return
+    }
+    log.Print("Reloading the themes...")
+
+    defaultTheme, err := loadTheme("", "")
+    if err != nil {
+        log.Printf("Failed to reload the default theme, keeping the last one: %+v", err)
+    }
+    themes, failed := loadThemes(config.Get().ThemesDir)
+
+    p.themeLock.Lock()
+    defer p.themeLock.Unlock()
+
+    if defaultTheme != nil {
+        p.defaultTheme = defaultTheme
+    }
+    for name, err := range failed {
+        if name == "" {
+            // The directory itself failed, so keep every theme
+            for oldName, old := range p.themes {
+                if _, ok := themes[oldName]; !ok {
+                    themes[oldName] = old
+                }
+            }
+        } else if old, ok := p.themes[name]; ok {
+            themes[name] = old
+        }
+        log.Printf("Failed to reload theme '%s', keeping the last one (if any): %+v", name, err)
+    }
+    p.themes = themes
+    p.themeErrors = failed
+    p.themeFiles = files
+}
+
+// localFilesChanged checks whether the local copies of the spreadsheet were
+// modified. It may be replaced on tests.
+var localFilesChanged func() bool = mtcareers.LocalFilesChanged
+
+// reloadLocalFiles checks whether the local copies of the spreadsheet were
+// modified and, if so, purges the cache and downloads every cached user once
+// again, so they are sent to every event stream following them
+func reloadLocalFiles() {
+    if !localFilesChanged() {
+        return
+    }
+
+    users := _cache.usernames()
+    log.Printf("Reloading %d cached players from the modified local files...", len(users))
+    PurgeAll()
+    for _, username := range users {
+        _, _, err := _cache.fetch(username, username)
+        if err != nil {
+            log.Printf("Failed to reload '%s': %+v", username, err)
+        }
+    }
+}
+
+// watchThemes reloads every modified CSS and template file, and every player
+// if the local copies of the spreadsheet were modified, on each interval,
+// until stop gets closed
+func (p *pageServer) watchThemes(interval time.Duration, stop chan struct{}) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-stop:
+            return
+        case <-ticker.C:
+            p.reloadThemes()
+            reloadLocalFiles()
+        }
+    }
+}
@@ -0,0 +1,133 @@
+package page
+
+import (
+    "encoding/json"
+    "github.com/SirGFM/MTTitleCard/config"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// touch writes a file, making sure its modification time changes
+func touch(t *testing.T, path, content string, age int) {
+    writeFile(t, path, content)
+    mtime := time.Now().Add(time.Duration(age) * time.Minute)
+    err := os.Chtimes(path, mtime, mtime)
+    if err != nil {
+        t.Fatalf("Failed to touch '%s': %+v", path, err)
+    }
+}
+
+// getBody requests path from the server, returning the response's body
+func getBody(ps *pageServer, path string) string {
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+    return w.Body.String()
+}
+
+func TestReloadThemes(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        var p Player
+        p.Data.Username = username
+        return p, nil
+    })
+
+    dir := t.TempDir()
+    tmpl := filepath.Join(dir, "template.html")
+    themes := filepath.Join(dir, "themes")
+    touch(t, tmpl, `first {{.Username}}`, -2)
+    touch(t, filepath.Join(themes, "dark", playerPage), `dark {{.Username}}`, -2)
+
+    cfg := config.Get()
+    cfg.TemplateFile = tmpl
+    cfg.ThemesDir = themes
+    err := config.LoadConfig(cfg)
+    if err != nil {
+        t.Fatalf("Failed to load the configuration: %+v", err)
+    }
+
+    var ps pageServer
+    _, err = newPageServer(&ps)
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    for _, tc := range []struct {
+        file string
+        content string
+        path string
+        expected string
+    } {
+        {tmpl, `second {{.Username}}`, "/GFM", "second GFM"},
+        // A broken template keeps the last good one
+        {tmpl, `broken {{.Username`, "/GFM", "second GFM"},
+        {tmpl, `third {{.Username}}`, "/GFM", "third GFM"},
+        {filepath.Join(themes, "dark", playerPage), `darker {{.Username}}`, "/theme/dark/GFM", "darker GFM"},
+        {filepath.Join(themes, "dark", playerPage), `{{.Username`, "/theme/dark/GFM", "darker GFM"},
+        {filepath.Join(themes, "light", playerPage), `light {{.Username}}`, "/theme/light/GFM", "light GFM"},
+    } {
+        touch(t, tc.file, tc.content, 0)
+        ps.reloadThemes()
+
+        body := getBody(&ps, tc.path)
+        if !strings.Contains(body, tc.expected) {
+            t.Fatalf("After writing '%s', expected '%s' but got '%s'", tc.content, tc.expected, body)
+        }
+    }
+}
+
+func TestReloadLocalFiles(t *testing.T) {
+    var wins int32
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        var p Player
+        p.Data.Username = username
+        p.Data.Wins = int(atomic.AddInt32(&wins, 1))
+        return p, nil
+    })
+    count := setupLeaderboard(t, nil)
+
+    var changed bool
+    old := localFilesChanged
+    localFilesChanged = func() bool {
+        return changed
+    }
+    t.Cleanup(func() {
+        localFilesChanged = old
+    })
+
+    _, err := getPlayer("GFM", "GFM")
+    if err != nil {
+        t.Fatalf("Failed to get user: %+v", err)
+    }
+    cachedUsers()
+
+    ch := _events.subscribe(playerTopic("GFM"))
+    defer _events.unsubscribe(playerTopic("GFM"), ch)
+
+    reloadLocalFiles()
+    if n := atomic.LoadInt32(&wins); n != 1 {
+        t.Fatalf("Expected nothing to be reloaded while the files are unchanged, got %d downloads", n)
+    }
+
+    changed = true
+    reloadLocalFiles()
+    select {
+    case ev := <-ch:
+        var d Data
+        err = json.Unmarshal(ev.data, &d)
+        if err != nil || ev.name != "player" || d.Wins != 2 {
+            t.Errorf("Expected the reloaded player to be published, got '%s': %+v (%+v)", ev.name, d, err)
+        }
+    default:
+        t.Errorf("Expected the reloaded player to be published")
+    }
+
+    cachedUsers()
+    if n := atomic.LoadInt32(count); n != 2 {
+        t.Errorf("Expected the leaderboard to be downloaded again, got %d downloads", n)
+    }
+}
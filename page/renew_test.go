@@ -0,0 +1,278 @@
+package page
+
+import (
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "html"
+    "github.com/SirGFM/MTTitleCard/config"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// setupOAuth points the credential and token files to a temporary directory,
+// authenticating through a fake OAuth server. Its auth endpoint redirects back
+// with a code bound to the PKCE challenge, while its token endpoint accepts
+// either those codes or "good-code". Returns the path to the token file.
+func setupOAuth(t *testing.T) string {
+    var lock sync.Mutex
+    challenges := map[string]string{}
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("/auth", func(w http.ResponseWriter, req *http.Request) {
+        query := req.URL.Query()
+        if query.Get("code_challenge_method") != "S256" {
+            http.Error(w, "Missing the PKCE challenge", http.StatusBadRequest)
+            return
+        }
+
+        lock.Lock()
+        code := fmt.Sprintf("code-%d", len(challenges))
+        challenges[code] = query.Get("code_challenge")
+        lock.Unlock()
+
+        redirect, _ := url.Parse(query.Get("redirect_uri"))
+        redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+        http.Redirect(w, req, redirect.String(), http.StatusFound)
+    })
+    mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+        req.ParseForm()
+        code, verifier := req.PostFormValue("code"), req.PostFormValue("code_verifier")
+
+        lock.Lock()
+        challenge, ok := challenges[code]
+        delete(challenges, code)
+        lock.Unlock()
+
+        sum := sha256.Sum256([]byte(verifier))
+        if ok && challenge != base64.RawURLEncoding.EncodeToString(sum[:]) {
+            http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+            return
+        } else if !ok && (code != "good-code" || verifier == "") {
+            http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(w, `{"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":3600}`)
+    })
+    ts := httptest.NewServer(mux)
+    t.Cleanup(ts.Close)
+
+    dir := t.TempDir()
+    cred := filepath.Join(dir, "credentials.json")
+    writeFile(t, cred, fmt.Sprintf(`{"installed":{"client_id":"id","client_secret":"secret",`+
+            `"redirect_uris":["http://localhost"],"auth_uri":"%s/auth","token_uri":"%s/token"}}`,
+            ts.URL, ts.URL))
+
+    cfg := config.Get()
+    cfg.CredentialFile = cred
+    cfg.TokenFile = filepath.Join(dir, "token.json")
+    cfg.AdminPassword = "secret"
+    cfg.AdminToken = "admin-token"
+    cfg.OAuthRedirectUrl = ""
+    config.LoadConfig(cfg)
+
+    return cfg.TokenFile
+}
+
+// checkSavedToken verifies that the fake server's token was saved
+func checkSavedToken(t *testing.T, tokenFile string) {
+    f, err := os.Open(tokenFile)
+    if err != nil {
+        t.Fatalf("Failed to open the token file: %+v", err)
+    }
+    defer f.Close()
+    var tok struct {
+        AccessToken string `json:"access_token"`
+    }
+    err = json.NewDecoder(f).Decode(&tok)
+    if err != nil || tok.AccessToken != "access" {
+        t.Errorf("Failed to save the token: %+v %+v", tok, err)
+    }
+}
+
+func TestRenewRequiresAdmin(t *testing.T) {
+    setupCache(t, 0, nil)
+    setupOAuth(t)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    for _, tc := range []struct {
+        method string
+        auth func(*http.Request)
+        status int
+    } {
+        {"GET", func(*http.Request) {}, http.StatusUnauthorized},
+        {"POST", func(*http.Request) {}, http.StatusUnauthorized},
+        {"GET", func(req *http.Request) { req.SetBasicAuth("admin", "wrong") }, http.StatusUnauthorized},
+        {"GET", func(req *http.Request) { req.Header.Set("Authorization", "Bearer wrong") }, http.StatusUnauthorized},
+        {"GET", func(req *http.Request) { req.SetBasicAuth("admin", "secret") }, http.StatusOK},
+        {"GET", func(req *http.Request) { req.Header.Set("Authorization", "Bearer admin-token") }, http.StatusOK},
+    } {
+        req := httptest.NewRequest(tc.method, "/", nil)
+        tc.auth(req)
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, req)
+        if w.Code != tc.status {
+            t.Errorf("%s %+v: expected status %d, got %d", tc.method, req.Header, tc.status, w.Code)
+        }
+    }
+}
+
+func TestRenewState(t *testing.T) {
+    setupCache(t, 0, nil)
+    tokenFile := setupOAuth(t)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    getState := func() string {
+        req := httptest.NewRequest("GET", "/", nil)
+        req.SetBasicAuth("admin", "secret")
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, req)
+
+        m := regexp.MustCompile(`name="state" value="([0-9a-f]+)"`).FindStringSubmatch(w.Body.String())
+        if m == nil {
+            t.Fatalf("Expected a state in the renew page: %s", w.Body.String())
+        } else if !strings.Contains(w.Body.String(), "state=" + m[1]) {
+            t.Fatalf("Expected the state in the auth URL: %s", w.Body.String())
+        }
+        return m[1]
+    }
+    post := func(state, code string) int {
+        form := url.Values{"state": {state}, "token": {code}}
+        req := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+        req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+        req.SetBasicAuth("admin", "secret")
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, req)
+        return w.Code
+    }
+
+    first, second := getState(), getState()
+    if first == second {
+        t.Fatalf("Expected a new state on each access")
+    }
+
+    if code := post("state-token", "good-code"); code != http.StatusBadRequest {
+        t.Errorf("Expected an unknown state to be rejected, got %d", code)
+    }
+    if code := post(first, "bad-code"); code != http.StatusBadGateway {
+        t.Errorf("Expected the bad code to be rejected by the token endpoint, got %d", code)
+    }
+    if code := post(first, "good-code"); code != http.StatusBadRequest {
+        t.Errorf("Expected a state to be used only once, got %d", code)
+    }
+    if code := post(second, "good-code"); code != http.StatusOK {
+        t.Fatalf("Expected the token to be saved, got %d", code)
+    }
+
+    checkSavedToken(t, tokenFile)
+}
+
+func TestOAuthCallback(t *testing.T) {
+    setupCache(t, 0, nil)
+    tokenFile := setupOAuth(t)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    // Start the authentication from the renew page
+    req := httptest.NewRequest("GET", "/", nil)
+    req.SetBasicAuth("admin", "secret")
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, req)
+    m := regexp.MustCompile(`<a href="([^"]+)"`).FindStringSubmatch(w.Body.String())
+    if m == nil {
+        t.Fatalf("Expected the auth URL in the renew page: %s", w.Body.String())
+    }
+    authUrl := html.UnescapeString(m[1])
+
+    // Authorize the server on the fake OAuth server, without following the
+    // redirect back to the server
+    client := &http.Client {
+        CheckRedirect: func(*http.Request, []*http.Request) error {
+            return http.ErrUseLastResponse
+        },
+    }
+    resp, err := client.Get(authUrl)
+    if err != nil {
+        t.Fatalf("Failed to access the auth URL: %+v", err)
+    }
+    resp.Body.Close()
+    callback, err := url.Parse(resp.Header.Get("Location"))
+    if err != nil || resp.StatusCode != http.StatusFound {
+        t.Fatalf("Expected a redirect from the auth URL, got %d (%+v)", resp.StatusCode, err)
+    }
+    if callback.Path != "/oauth/callback" || callback.Host != fmt.Sprintf("localhost:%d", config.Get().Port) {
+        t.Fatalf("Expected a redirect to the server, got '%s'", callback)
+    }
+
+    get := func(uri string) int {
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, httptest.NewRequest("GET", uri, nil))
+        return w.Code
+    }
+
+    if code := get("/oauth/callback?error=access_denied"); code != http.StatusBadRequest {
+        t.Errorf("Expected a denied authorization to be rejected, got %d", code)
+    }
+    if code := get("/oauth/callback?code=good-code&state=bad-state"); code != http.StatusBadRequest {
+        t.Errorf("Expected an unknown state to be rejected, got %d", code)
+    }
+    if code := get(callback.RequestURI()); code != http.StatusOK {
+        t.Fatalf("Expected the token to be saved, got %d", code)
+    }
+    if code := get(callback.RequestURI()); code != http.StatusBadRequest {
+        t.Errorf("Expected a state to be used only once, got %d", code)
+    }
+
+    checkSavedToken(t, tokenFile)
+}
+
+func TestRenewHiddenWithServiceAccount(t *testing.T) {
+    setupCache(t, 0, nil)
+    setupOAuth(t)
+
+    cfg := config.Get()
+    cfg.ServiceAccountFile = filepath.Join(t.TempDir(), "service-account.json")
+    config.LoadConfig(cfg)
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    for _, tc := range []struct {
+        method string
+        uri string
+    } {
+        {"GET", "/"},
+        {"POST", "/"},
+        {"GET", "/oauth/callback?code=good-code&state=state"},
+    } {
+        req := httptest.NewRequest(tc.method, tc.uri, nil)
+        req.SetBasicAuth("admin", "secret")
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, req)
+        if w.Code != http.StatusNotFound {
+            t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.uri, http.StatusNotFound, w.Code)
+        }
+    }
+}
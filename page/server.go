@@ -1,6 +1,7 @@
 package page
 
 import (
+    "bytes"
     "fmt"
     "github.com/pkg/errors"
     "github.com/SirGFM/MTTitleCard/config"
@@ -8,6 +9,12 @@ import (
     "html/template"
     "log"
     "net/http"
+    "net/url"
+    "path"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
 )
 
 // Public pageServer interface
@@ -17,10 +24,33 @@ type PageServer interface {
 
 // pageServer wraps the request handler, and everything used by it
 type pageServer struct {
-    // userPage is a template used to fill a user's info
-    userPage *template.Template
+    // defaultTheme is used to render pages whenever no theme is requested
+    defaultTheme *theme
+    // themes maps the name of every successfully loaded theme to it
+    themes map[string]*theme
+    // themeErrors maps the name of every theme that failed to load to its
+    // error
+    themeErrors map[string]error
+    // themeFiles maps every file within the themes directory to when it was
+    // last modified
+    themeFiles map[string]time.Time
+    // themeLock synchronizes access to the themes, since they may be
+    // reloaded while the server is running
+    themeLock sync.RWMutex
+    // stopReload is closed to stop reloading modified themes
+    stopReload chan struct{}
+    // stopTokenCheck is closed to stop checking whether the token was revoked
+    stopTokenCheck chan struct{}
     // renewPage is a template used to renew the server's token
     renewPage *template.Template
+    // livePage is a template used to display whoever is on air
+    livePage *template.Template
+    // producerPage is a template used to select who is on air
+    producerPage *template.Template
+    // adminPage is a template used to inspect the server's state
+    adminPage *template.Template
+    // live stores the players currently on air
+    live liveState
     // httpServer handling requests from the client
     httpServer *http.Server
 }
@@ -37,104 +67,336 @@ type request struct {
     req *http.Request
     // path is the received URL (e.g., "index", "favicon.ico
     path string
+    // theme used to render the requested page
+    theme *theme
+    // serviceUri used to access resources from the requested theme
+    serviceUri string
 }
 
-// getCss retrieve the CSS used by the server
+// selectTheme for the request, either from a "theme/{name}/" prefix in the path
+// or from a "theme" query parameter. If the theme doesn't exist, an error is
+// sent to the client and false is returned.
+func (r *request) selectTheme() bool {
+    r.p.themeLock.RLock()
+    defer r.p.themeLock.RUnlock()
+
+    r.theme = r.p.defaultTheme
+    r.serviceUri = config.Get().ServiceUri
+
+    name := r.req.URL.Query().Get("theme")
+    if base, rest := splitPath(r.path); base == "theme" {
+        name, r.path = splitPath(rest)
+    }
+    if name == "" {
+        return true
+    }
+
+    t, ok := r.p.themes[name]
+    if !ok {
+        http.Error(r.w, fmt.Sprintf("Unknown theme '%s'", name), http.StatusNotFound)
+        return false
+    }
+    r.theme = t
+    // Route every resource (e.g., the CSS) through the theme's path
+    r.serviceUri = fmt.Sprintf("%s/theme/%s", config.Get().ServiceUri, url.PathEscape(name))
+    return true
+}
+
+// render a page from the request's theme, sending it to the client
+func (r *request) render(page, contentType string, data interface{}) {
+    r.renderStatus(page, contentType, http.StatusOK, data)
+}
+
+// renderStatus renders a page from the request's theme, sending it to the
+// client with the given status code
+func (r *request) renderStatus(page, contentType string, status int, data interface{}) {
+    r.renderPageStatus(r.theme.pages[page], contentType, status, data)
+}
+
+// renderPage executes the template, sending it to the client. If the template
+// fails, the error is sent instead.
+func (r *request) renderPage(tmpl *template.Template, contentType string, data interface{}) {
+    r.renderPageStatus(tmpl, contentType, http.StatusOK, data)
+}
+
+// renderPageStatus executes the template, sending it to the client with the
+// given status code. If the template fails, the error is sent instead.
+func (r *request) renderPageStatus(tmpl *template.Template, contentType string, status int, data interface{}) {
+    var buf bytes.Buffer
+
+    err := tmpl.Execute(&buf, data)
+    if err != nil {
+        serr := fmt.Sprintf("%+v", err)
+        http.Error(r.w, serr, http.StatusInternalServerError)
+        log.Print(serr)
+        return
+    }
+
+    r.w.Header().Set("Content-Type", contentType)
+    r.w.WriteHeader(status)
+    r.w.Write(buf.Bytes())
+}
+
+// getCss retrieve the CSS used by the requested theme
 func (r *request) getCss() {
     r.w.Header().Set("Content-Type", "text/css")
     r.w.WriteHeader(http.StatusOK)
-    config.Get().WriteCss(r.w, []byte(style))
+    r.w.Write(r.theme.css)
+}
+
+// getAsset retrieve a file from the requested theme's assets
+func (r *request) getAsset(file string) {
+    if r.theme.assets == "" {
+        http.Error(r.w, "Theme doesn't have any assets", http.StatusNotFound)
+        return
+    }
+
+    // Cleaning the path from the root removes any ".." from it
+    file = path.Clean("/" + file)
+    http.ServeFile(r.w, r.req, filepath.Join(r.theme.assets, filepath.FromSlash(file)))
 }
 
 // getUserData parse username info, fit it into the template and return the
-// resulting page. In case of error, the stack trace is returned back to the
-// client
+// resulting page. In case of error, the theme's page for that error is
+// returned instead.
 func (r *request) getUserData(username string) {
-    var data Data
-
-    err := GenerateData(username, username)
-    if err == nil {
-        data = _cache[username]
-
-        // XXX: The careers was last updated for MT14,
-        //      so MtCount is wrong... :grimacing:
-        data.MtCount += 1
-    } else {
-        data = Data {
-            Channel: "It's a mystery",
-            Username: username,
-            Joined: "Just now!",
-            WinRate: "0",
-            HighestPlacement: "This round",
-            MtCount: 1,
-        }
+    p, err := lookupPlayer(username)
+    if err != nil {
+        r.renderError(username, err)
+        return
+    }
+    data := p.Data
+    data.ServiceUri = r.serviceUri
+
+    r.render(playerPage, "text/html", data)
+}
 
+// getUserSvg parse username info and fit it into the SVG template. In case of
+// error, a placeholder user is rendered along with the error's status code.
+func (r *request) getUserSvg(username string) {
+    p, err := lookupPlayer(username)
+    status := http.StatusOK
+    if err != nil {
+        status, _ = errorStatus(err)
+    }
+    data := p.Data
+    data.ServiceUri = r.serviceUri
+
+    r.renderStatus(svgPage, "image/svg+xml", status, data)
+}
+
+// lookupPlayer retrieves a user. On failure, the error is logged and returned
+// along with a placeholder user (most likely someone still missing from the
+// spreadsheet), so the card may still be rendered.
+func lookupPlayer(username string) (Player, error) {
+    p, err := getPlayer(username, username)
+    if err != nil {
         serr := fmt.Sprintf("%+v", err)
         log.Print(serr)
-    }
-    data.ServiceUri = config.Get().ServiceUri
 
-    r.w.Header().Set("Content-Type", "text/html")
-    r.w.WriteHeader(http.StatusOK)
-    r.p.userPage.Execute(r.w, data)
+        p = mysteryPlayer(username)
+    }
+    return p, err
 }
 
 // Data supplied to the renew token page
 type RenewData struct {
     Url string
+    // State is the OAuth state within Url, sent back with the token
+    State string
+    // Revoked is set if the token must be generated again because Google
+    // rejected it
+    Revoked bool
+}
+
+// checkRenewable verifies that the token may be renewed, which isn't the case
+// when using a service account or local files. Otherwise, an error is sent to
+// the client and false is returned.
+func (r *request) checkRenewable() bool {
+    if mtcareers.UsesLocalFiles() {
+        http.Error(r.w, "The server reads the data from local files, so there's no token to renew", http.StatusNotFound)
+        return false
+    } else if mtcareers.UsesServiceAccount() {
+        http.Error(r.w, "The server authenticates through a service account, so there's no token to renew", http.StatusNotFound)
+        return false
+    }
+    return true
 }
 
 // getRenewToken and display it to the client, with a form to post the
 // renewed token
 func (r *request) getRenewToken() {
-    url, err := mtcareers.CheckToken()
+    if !r.checkRenewable() {
+        return
+    }
+    url, state, err := mtcareers.CheckToken()
     if err != nil {
+        // Most likely the credential file is missing or broken
         serr := fmt.Sprintf("%+v", err)
-        http.Error(r.w, serr, http.StatusNotFound)
+        http.Error(r.w, serr, http.StatusInternalServerError)
         log.Print(serr)
         return
     }
     d := RenewData {
         Url: url,
+        State: state,
+        Revoked: mtcareers.TokenRevoked(),
     }
     r.w.Header().Set("Content-Type", "text/html")
     r.w.WriteHeader(http.StatusOK)
     r.p.renewPage.Execute(r.w, d)
 }
 
+// splitPath splits the first component of a path from the rest of it. For
+// example, "api/v1/players" is split into "api" and "v1/players".
+func splitPath(path string) (string, string) {
+    if idx := strings.IndexByte(path, '/'); idx != -1 {
+        return path[:idx], path[idx+1:]
+    }
+    return path, ""
+}
+
 // get handles GET requests
 func (r *request) get() {
-    switch r.path {
+    base, rest := splitPath(r.path)
+    switch base {
     case "style.css":
         r.getCss()
     case "",
         "index",
         "index.html":
 
-        r.getRenewToken()
+        if r.checkAdmin() {
+            r.getRenewToken()
+        }
     case "favicon.ico":
         http.Error(r.w, "Missing a favicon...", http.StatusNotFound)
+    case "api":
+        r.getApi(rest)
+    case "vs":
+        r.getMatch(rest)
+    case "assets":
+        r.getAsset(rest)
+    case "events":
+        r.getEvents(rest)
+    case "live":
+        r.getLive(rest)
+    case "producer":
+        r.getProducer()
+    case "leaderboard":
+        r.getLeaderboard()
+    case "tournament":
+        r.getTournament()
+    case "admin":
+        r.getAdmin()
+    case "healthz":
+        r.getHealth()
+    case "readyz":
+        r.getReady()
+    case "metrics":
+        r.getMetrics()
+    case "oauth":
+        if rest == "callback" {
+            r.getOAuthCallback()
+        } else {
+            http.Error(r.w, fmt.Sprintf("Unknown resource '%s'", r.path), http.StatusNotFound)
+        }
     default:
-        r.getUserData(r.path)
+        r.getUser(r.path)
     }
 }
 
-// post handles POST requests
+// getUser returns a user's title card, in the format requested through the
+// path's extension (e.g., "GFM.png"). Without any known extension, the title
+// card is returned as a HTML page.
+func (r *request) getUser(path string) {
+    ext := filepath.Ext(path)
+    username := strings.TrimSuffix(path, ext)
+
+    switch ext {
+    case ".png":
+        r.getUserPng(username)
+    case ".svg":
+        r.getUserSvg(username)
+    default:
+        r.getUserData(path)
+    }
+}
+
+// post handles POST requests, which were already verified to come from an
+// admin
 func (r *request) post() {
+    base, rest := splitPath(r.path)
+    switch base {
+    case "refresh":
+        r.postRefresh(rest)
+    case "producer":
+        r.postProducer()
+    case "admin":
+        r.postAdmin(rest)
+    default:
+        r.postToken()
+    }
+}
+
+// postToken saves the token generated by the user, as supplied in the form
+// from the renew token page
+func (r *request) postToken() {
+    if !r.checkRenewable() {
+        return
+    }
     // XXX: This was really rushed... D:
     err := r.req.ParseForm()
     if err != nil {
         serr := fmt.Sprintf("%+v", err)
-        http.Error(r.w, serr, http.StatusNotFound)
+        http.Error(r.w, serr, http.StatusBadRequest)
         log.Print(serr)
         return
     }
 
     tok := r.req.PostFormValue("token")
-    err = mtcareers.SaveAuthentication(tok)
-    if err != nil {
+    if tok == "" {
+        http.Error(r.w, "Missing the token", http.StatusBadRequest)
+        return
+    }
+    r.saveAuthentication(tok, r.req.PostFormValue("state"))
+}
+
+// getOAuthCallback receives the user back from Google, after they authorized
+// the server, and saves the token generated from the supplied code
+func (r *request) getOAuthCallback() {
+    if !r.checkRenewable() {
+        return
+    }
+    query := r.req.URL.Query()
+    if msg := query.Get("error"); msg != "" {
+        serr := fmt.Sprintf("The authorization was denied: %s", msg)
+        http.Error(r.w, serr, http.StatusBadRequest)
+        log.Print(serr)
+        return
+    }
+
+    code := query.Get("code")
+    if code == "" {
+        http.Error(r.w, "Missing the authorization code", http.StatusBadRequest)
+        return
+    }
+    r.saveAuthentication(code, query.Get("state"))
+}
+
+// saveAuthentication exchanges the authorization code for a token, reporting
+// the result to the client
+func (r *request) saveAuthentication(code, state string) {
+    err := mtcareers.SaveAuthentication(code, state)
+    if errors.Cause(err) == mtcareers.ErrInvalidState {
+        serr := fmt.Sprintf("%+v", err)
+        http.Error(r.w, serr, http.StatusBadRequest)
+        log.Print(serr)
+        return
+    } else if err != nil {
+        // Most likely Google rejected the token
         serr := fmt.Sprintf("%+v", err)
-        http.Error(r.w, serr, http.StatusNotFound)
+        http.Error(r.w, serr, http.StatusBadGateway)
         log.Print(serr)
         return
     }
@@ -144,27 +406,62 @@ func (r *request) post() {
     r.w.Write([]byte("<h1>Token saved successfully!</h1>"))
 }
 
+// delete handles DELETE requests, which were already verified to come from an
+// admin, removing users from the cache so they are downloaded again on their
+// next access. Every other route (e.g., "admin") is rejected.
+func (r *request) delete() {
+    base, rest := splitPath(r.path)
+    switch {
+    case r.path == "", r.path == "index", r.path == "index.html":
+        PurgeAll()
+    case routes[base]:
+        http.Error(r.w, "Not a user", http.StatusBadRequest)
+        return
+    case rest != "" || strings.HasSuffix(r.path, "/"):
+        http.Error(r.w, "Expected a player: /{name}", http.StatusNotFound)
+        return
+    default:
+        Purge(r.path)
+    }
+
+    r.w.WriteHeader(http.StatusNoContent)
+}
+
 // ServeHTTP is called by Go's http package whenever a new HTTP request arrives
 func (p *pageServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
     log.Printf("New request from %+v: %s %+v", req.RemoteAddr, req.Method, req.URL.Path)
+    sw := &statusWriter {
+        ResponseWriter: w,
+    }
     var r request = request {
         p: p,
-        w: w,
+        w: sw,
         req: req,
         path: req.URL.Path,
     }
+    defer r.observe(sw, time.Now())
 
     if r.path[0] == '/' {
         r.path = r.path[1:]
     }
+    if !r.selectTheme() {
+        return
+    }
 
     switch (req.Method) {
     case "GET":
         r.get()
     case "POST":
-        r.post()
+        // Every request that changes the server's state requires an admin
+        if r.checkAdmin() {
+            r.post()
+        }
+    case "DELETE":
+        if r.checkAdmin() {
+            r.delete()
+        }
     default:
-        w.WriteHeader(http.StatusMethodNotAllowed)
+        r.w.WriteHeader(http.StatusMethodNotAllowed)
     }
 }
 
@@ -175,10 +472,20 @@ func newPageServer(ps *pageServer) (PageServer, error) {
         ps = &pageServer{}
     }
 
-    ps.userPage = template.New("")
-    _, err = ps.userPage.Parse(config.Get().PageTemplate(pageTemplate))
+    ps.defaultTheme, err = loadTheme("", "")
     if err != nil {
-        return nil, errors.Wrap(err, "Failed to parse template page")
+        return nil, errors.Wrap(err, "Failed to load the default theme")
+    }
+
+    // Themes that fail to load are simply left out, so a broken theme
+    // doesn't stop every other page from being served
+    ps.themeFiles = themeModTimes(config.Get().ThemesDir)
+    ps.themes, ps.themeErrors = loadThemes(config.Get().ThemesDir)
+    for name, err := range ps.themeErrors {
+        log.Printf("Failed to load theme '%s': %+v", name, err)
+    }
+    for name := range ps.themes {
+        log.Printf("Loaded theme '%s'", name)
     }
 
     ps.renewPage = template.New("")
@@ -187,6 +494,24 @@ func newPageServer(ps *pageServer) (PageServer, error) {
         return nil, errors.Wrap(err, "Failed to parse renew server template page")
     }
 
+    ps.livePage = template.New("")
+    _, err = ps.livePage.Parse(liveTemplate)
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to parse live template page")
+    }
+
+    ps.producerPage = template.New("")
+    _, err = ps.producerPage.Parse(producerTemplate)
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to parse producer template page")
+    }
+
+    ps.adminPage = template.New("")
+    _, err = ps.adminPage.Parse(adminTemplate)
+    if err != nil {
+        return nil, errors.Wrap(err, "Failed to parse admin template page")
+    }
+
     return ps, nil
 }
 
@@ -212,11 +537,37 @@ func StartServer(port int) error {
         return err
     }
 
+    err = generateAdminPassword()
+    if err != nil {
+        return err
+    }
+
     go func() {
         log.Print("Waiting...")
         srv.httpServer.ListenAndServe()
     } ()
 
+    // Report missing headers early, instead of on the first request. Without
+    // a token, they are reported once the data is first retrieved.
+    if mtcareers.ValidToken() == nil {
+        go func() {
+            err := mtcareers.CheckHeaders()
+            if err != nil {
+                log.Printf("Failed to map every column of the spreadsheet: %+v", err)
+            }
+        } ()
+    }
+
+    if interval := config.Get().ReloadInterval; interval > 0 {
+        srv.stopReload = make(chan struct{})
+        go srv.watchThemes(time.Duration(interval) * time.Second, srv.stopReload)
+    }
+
+    if interval := config.Get().TokenCheckInterval; interval > 0 {
+        srv.stopTokenCheck = make(chan struct{})
+        go watchToken(time.Duration(interval) * time.Second, srv.stopTokenCheck)
+    }
+
     return nil
 }
 
@@ -226,4 +577,12 @@ func StopServer() {
         srv.httpServer.Close()
         srv.httpServer = nil
     }
+    if srv.stopReload != nil {
+        close(srv.stopReload)
+        srv.stopReload = nil
+    }
+    if srv.stopTokenCheck != nil {
+        close(srv.stopTokenCheck)
+        srv.stopTokenCheck = nil
+    }
 }
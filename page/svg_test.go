@@ -0,0 +1,44 @@
+package page
+
+import (
+    "encoding/xml"
+    "io"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestSvgEscapesMarkup(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        var p Player
+        p.Data.Username = `<script>alert("hi")</script>`
+        p.Data.Channel = username
+        p.Data.Avatar = `"/><script>alert(1)</script>`
+        return p, nil
+    })
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/GFM.svg", nil))
+    body := w.Body.String()
+    if ct := w.Header().Get("Content-Type"); ct != "image/svg+xml" {
+        t.Fatalf("Expected a SVG image, got '%s'", ct)
+    } else if strings.Contains(body, "<script>") {
+        t.Fatalf("Username wasn't escaped:\n%s", body)
+    }
+
+    // The output must still be well formed XML
+    dec := xml.NewDecoder(strings.NewReader(body))
+    for {
+        _, err := dec.Token()
+        if err == io.EOF {
+            break
+        } else if err != nil {
+            t.Fatalf("Generated an invalid SVG: %+v\n%s", err, body)
+        }
+    }
+}
@@ -39,9 +39,64 @@ body {
 }
 .stats_field {
 }
+.match {
+    margin: 1.5em;
+    width: 95%;
+    text-align: center;
+}
+.match_player {
+    width: 40%;
+    font-size: x-large;
+}
+.match_player .avatar {
+    float: none;
+    display: block;
+    margin: auto;
+    margin-bottom: 0.5em;
+}
+.match_label {
+    width: 20%;
+    font-size: small;
+}
+.match_field {
+    width: 40%;
+}
+.lead {
+    color: #f1c40f;
+}
+.leaderboard {
+    margin: 1.5em;
+    width: 95%;
+    text-align: center;
+}
+.leaderboard .avatar {
+    width: 32px;
+    float: none;
+    vertical-align: middle;
+    margin-right: 0.5em;
+}
+.leaderboard_rank {
+    width: 10%;
+}
+.leaderboard_player {
+    text-align: left;
+}
+.pages {
+    margin: 1.5em;
+    text-align: center;
+}
+.tournament {
+    margin: 1.5em;
+    width: 95%;
+}
+.tournament_title {
+    font-size: x-large;
+    text-align: center;
+}
 `
 
-// pageTemplate used to display a user's downloaded info
+// pageTemplate used to display a user's downloaded info. Every field tagged
+// with "data-field" is updated in place whenever the user's data changes.
 const pageTemplate = `
 <!DOCTYPE html>
 <html lang="en">
@@ -51,58 +106,584 @@ const pageTemplate = `
         <meta charset="UTF-8">
     </head>
     <body>
-        <label class="channel" id="channel">
-            twitch.tv/{{.Channel}}
-        </label>
+        <label class="channel" id="channel" data-field="Channel">twitch.tv/{{.Channel}}</label>
         <div class="user" id="user">
             {{if eq .Avatar "" }}
                 <!-- Didn't get avatar... -->
             {{else}}
-                <img class="avatar" src="{{.Avatar}}" alt="{{.Username}}'s avatar">
+                <img class="avatar" src="{{.Avatar}}" alt="{{.Username}}'s avatar" data-field="Avatar">
             {{end}}
-            <label class="username" id="username">
-                {{.Username}}
-            </label>
+            <label class="username" id="username" data-field="Username">{{.Username}}</label>
         </div>
         <table class="stats" id="stats"><tbody>
             <tr>
                 <td class="stats_label" id="stats_label">Joined</td>
-                <td class="stats_field" id="stats_field">{{.Joined}}</td>
+                <td class="stats_field" id="stats_field" data-field="Joined">{{.Joined}}</td>
             </tr>
             <tr>
                 <td class="stats_label" id="stats_label">MT Count</td>
-                <td class="stats_field" id="stats_field">{{.MtCount}}</td>
+                <td class="stats_field" id="stats_field" data-field="MtCount">{{.MtCount}}</td>
             </tr>
             <tr>
                 <td class="stats_label" id="stats_label">Wins</td>
-                <td class="stats_field" id="stats_field">{{.Wins}}</td>
+                <td class="stats_field" id="stats_field" data-field="Wins">{{.Wins}}</td>
             </tr>
             <tr>
                 <td class="stats_label" id="stats_label">Losses</td>
-                <td class="stats_field" id="stats_field">
-                    {{.Losses}}
-                    {{if eq .Losses 0 }}
-                        (Flawless!)
-                    {{end}}
-                </td>
+                <td class="stats_field" id="stats_field" data-field="Losses">{{.Losses}}{{if eq .Losses 0 }} (Flawless!){{end}}</td>
             </tr>
             <tr>
                 <td class="stats_label" id="stats_label">Win Rate</td>
-                <td class="stats_field" id="stats_field">{{.WinRate}}%</td>
+                <td class="stats_field" id="stats_field" data-field="WinRate">{{.WinRate}}%</td>
             </tr>
             <tr>
                 <td class="stats_label" id="stats_label">Draft Points</td>
-                <td class="stats_field" id="stats_field">{{.DraftPoints}}</td>
+                <td class="stats_field" id="stats_field" data-field="DraftPoints">{{.DraftPoints}}</td>
             </tr>
             <tr>
                 <td class="stats_label" id="stats_label">Highest Placement</td>
                 {{if eq .HighestPlacement "9999th" }}
-                    <td class="stats_field" id="stats_field">N/A</td>
+                    <td class="stats_field" id="stats_field" data-field="HighestPlacement">N/A</td>
                 {{else}}
-                    <td class="stats_field" id="stats_field">{{.HighestPlacement}}</td>
+                    <td class="stats_field" id="stats_field" data-field="HighestPlacement">{{.HighestPlacement}}</td>
                 {{end}}
             </tr>
         </tbody></table>
+        <script>
+            (function() {
+                if (!window.EventSource) {
+                    return;
+                }
+
+                // Format each field as done by the template
+                var format = {
+                    Channel: function(v) { return "twitch.tv/" + v; },
+                    Losses: function(v) { return v === 0 ? v + " (Flawless!)" : v; },
+                    WinRate: function(v) { return v + "%"; },
+                    HighestPlacement: function(v) { return v === "9999th" ? "N/A" : v; },
+                };
+
+                var url = {{.ServiceUri}} + "/events/" + encodeURIComponent({{.Username}});
+                var source = new EventSource(url);
+                source.addEventListener("player", function(e) {
+                    var data = JSON.parse(e.data);
+                    var fields = document.querySelectorAll("[data-field]");
+                    for (var i = 0; i < fields.length; i++) {
+                        var name = fields[i].getAttribute("data-field");
+                        var value = data[name];
+                        if (value === undefined) {
+                            continue;
+                        } else if (format[name]) {
+                            value = format[name](value);
+                        }
+
+                        if (fields[i].tagName === "IMG") {
+                            fields[i].src = value;
+                        } else {
+                            fields[i].textContent = value;
+                        }
+                    }
+                });
+            })();
+        </script>
+    </body>
+</html>
+`
+
+// matchTemplate used to display the downloaded info of both players in a match
+const matchTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title> {{.Left.Username}} vs {{.Right.Username}} </title>
+        <link rel="stylesheet" href="{{.ServiceUri}}/style.css">
+        <meta charset="UTF-8">
+    </head>
+    <body>
+        <table class="match" id="match"><tbody>
+            <tr>
+                {{template "player" .Left}}
+                <td class="match_label">vs</td>
+                {{template "player" .Right}}
+            </tr>
+            <tr>
+                <td class="match_field">{{.Left.Joined}}</td>
+                <td class="match_label">Joined</td>
+                <td class="match_field">{{.Right.Joined}}</td>
+            </tr>
+            <tr>
+                <td class="match_field">{{.Left.MtCount}}</td>
+                <td class="match_label">MT Count</td>
+                <td class="match_field">{{.Right.MtCount}}</td>
+            </tr>
+            <tr>
+                <td class="match_field{{if .Left.LeadsWins}} lead{{end}}">{{.Left.Wins}}</td>
+                <td class="match_label">Wins</td>
+                <td class="match_field{{if .Right.LeadsWins}} lead{{end}}">{{.Right.Wins}}</td>
+            </tr>
+            <tr>
+                <td class="match_field">{{.Left.Losses}}</td>
+                <td class="match_label">Losses</td>
+                <td class="match_field">{{.Right.Losses}}</td>
+            </tr>
+            <tr>
+                <td class="match_field{{if .Left.LeadsWinRate}} lead{{end}}">{{.Left.WinRate}}%</td>
+                <td class="match_label">Win Rate</td>
+                <td class="match_field{{if .Right.LeadsWinRate}} lead{{end}}">{{.Right.WinRate}}%</td>
+            </tr>
+            <tr>
+                <td class="match_field{{if .Left.LeadsDraftPoints}} lead{{end}}">{{.Left.DraftPoints}}</td>
+                <td class="match_label">Draft Points</td>
+                <td class="match_field{{if .Right.LeadsDraftPoints}} lead{{end}}">{{.Right.DraftPoints}}</td>
+            </tr>
+            <tr>
+                {{template "placement" .Left}}
+                <td class="match_label">Highest Placement</td>
+                {{template "placement" .Right}}
+            </tr>
+        </tbody></table>
+    </body>
+</html>
+
+{{define "player"}}
+    <td class="match_player">
+        {{if ne .Avatar "" }}
+            <img class="avatar" src="{{.Avatar}}" alt="{{.Username}}'s avatar">
+        {{end}}
+        {{.Username}}
+    </td>
+{{end}}
+
+{{define "placement"}}
+    {{if eq .HighestPlacement "9999th" }}
+        <td class="match_field">N/A</td>
+    {{else}}
+        <td class="match_field{{if .LeadsPlacement}} lead{{end}}">{{.HighestPlacement}}</td>
+    {{end}}
+{{end}}
+`
+
+// svgTemplate used to display a user's downloaded info as a SVG image. The
+// layout and colors follow the default style.
+const svgTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="480" height="392" viewBox="0 0 480 392">
+    <style>
+        text {
+            fill: #ffffff;
+            font-weight: bold;
+            font-family: sans-serif;
+            font-size: 16px;
+        }
+        .channel {
+            font-size: 13px;
+        }
+        .username {
+            font-size: 32px;
+        }
+    </style>
+    <rect width="100%" height="100%" fill="#202225"/>
+    <text class="channel" id="channel" x="456" y="25" text-anchor="end">twitch.tv/{{.Channel}}</text>
+    {{if eq .Avatar "" }}
+        <text class="username" id="username" x="24" y="122">{{.Username}}</text>
+    {{else}}
+        <image class="avatar" href="{{.Avatar}}" x="24" y="61" width="100" height="100"/>
+        <text class="username" id="username" x="136" y="122">{{.Username}}</text>
+    {{end}}
+    <g class="stats" id="stats">
+        <text class="stats_label" x="24" y="201">Joined</text>
+        <text class="stats_field" x="325" y="201">{{.Joined}}</text>
+        <text class="stats_label" x="24" y="229">MT Count</text>
+        <text class="stats_field" x="325" y="229">{{.MtCount}}</text>
+        <text class="stats_label" x="24" y="257">Wins</text>
+        <text class="stats_field" x="325" y="257">{{.Wins}}</text>
+        <text class="stats_label" x="24" y="285">Losses</text>
+        <text class="stats_field" x="325" y="285">{{.Losses}}{{if eq .Losses 0 }} (Flawless!){{end}}</text>
+        <text class="stats_label" x="24" y="313">Win Rate</text>
+        <text class="stats_field" x="325" y="313">{{.WinRate}}%</text>
+        <text class="stats_label" x="24" y="341">Draft Points</text>
+        <text class="stats_field" x="325" y="341">{{.DraftPoints}}</text>
+        <text class="stats_label" x="24" y="369">Highest Placement</text>
+        {{if eq .HighestPlacement "9999th" }}
+            <text class="stats_field" x="325" y="369">N/A</text>
+        {{else}}
+            <text class="stats_field" x="325" y="369">{{.HighestPlacement}}</text>
+        {{end}}
+    </g>
+</svg>
+`
+
+// liveTemplate used to display whoever is on air. Cards are loaded in a hidden
+// frame, and only displayed once they finish loading, so switching players
+// doesn't flicker.
+const liveTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title> MT Title Card - Live </title>
+        <meta charset="UTF-8">
+        <style>
+            html, body {
+                margin: 0;
+                width: 100%;
+                height: 100%;
+                overflow: hidden;
+                background: transparent;
+            }
+            iframe {
+                position: absolute;
+                width: 100%;
+                height: 100%;
+                border: none;
+                background: transparent;
+            }
+        </style>
+    </head>
+    <body>
+        {{if eq .Path "" }}
+            <iframe id="frame0" src="about:blank"></iframe>
+        {{else}}
+            <iframe id="frame0" src="{{.ServiceUri}}{{.Path}}"></iframe>
+        {{end}}
+        <iframe id="frame1" src="about:blank" style="visibility: hidden"></iframe>
+        <script>
+            (function() {
+                var frames = [
+                    document.getElementById("frame0"),
+                    document.getElementById("frame1"),
+                ];
+                var current = 0;
+
+                var source = new EventSource({{.ServiceUri}} + "/live/events");
+                source.addEventListener("live", function(e) {
+                    var data = JSON.parse(e.data);
+                    var src = data.Path ? {{.ServiceUri}} + data.Path : "about:blank";
+                    if (frames[current].getAttribute("src") === src) {
+                        return;
+                    }
+
+                    var next = frames[1 - current];
+                    next.onload = function() {
+                        next.onload = null;
+                        next.style.visibility = "visible";
+                        frames[current].style.visibility = "hidden";
+                        current = 1 - current;
+                    };
+                    next.setAttribute("src", src);
+                });
+            })();
+        </script>
+    </body>
+</html>
+`
+
+// leaderboardTemplate used to display every player ranked by a given stat
+const leaderboardTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title> MT Leaderboard - {{.MetricLabel}} </title>
+        <link rel="stylesheet" href="{{.ServiceUri}}/style.css">
+        <meta charset="UTF-8">
+    </head>
+    <body>
+        <div class="pages">
+            Sort by:
+            {{$uri := .ServiceUri}}
+            {{$current := .Metric}}
+            {{$perPage := .PerPage}}
+            {{range .Metrics}}
+                {{if eq .Name $current}}
+                    <span class="lead">{{.Label}}</span>
+                {{else}}
+                    <a href="{{$uri}}/leaderboard?sort={{.Name}}&perPage={{$perPage}}">{{.Label}}</a>
+                {{end}}
+            {{end}}
+        </div>
+        <table class="leaderboard"><tbody>
+            <tr>
+                <th class="leaderboard_rank">#</th>
+                <th class="leaderboard_player">Player</th>
+                <th>MT Count</th>
+                <th>Wins</th>
+                <th>Losses</th>
+                <th>Win Rate</th>
+                <th>Draft Points</th>
+                <th>Highest Placement</th>
+            </tr>
+            {{range .Entries}}
+                <tr>
+                    <td class="leaderboard_rank">{{.Rank}}</td>
+                    <td class="leaderboard_player">
+                        {{if ne .Avatar "" }}
+                            <img class="avatar" src="{{.Avatar}}" alt="{{.Username}}'s avatar">
+                        {{end}}
+                        <a href="{{$uri}}/{{.Username}}">{{.Username}}</a>
+                    </td>
+                    <td>{{.MtCount}}</td>
+                    <td>{{.Wins}}</td>
+                    <td>{{.Losses}}</td>
+                    <td>{{.WinRate}}%</td>
+                    <td>{{.DraftPoints}}</td>
+                    <td>{{if eq .HighestPlacement "9999th" }}N/A{{else}}{{.HighestPlacement}}{{end}}</td>
+                </tr>
+            {{end}}
+        </tbody></table>
+        <div class="pages">
+            {{if .Prev}}
+                <a href="{{.ServiceUri}}/leaderboard?sort={{.Metric}}&page={{.Prev}}&perPage={{.PerPage}}">&lt; Previous</a>
+            {{end}}
+            Page {{.Page}} of {{.Pages}}
+            {{if .Next}}
+                <a href="{{.ServiceUri}}/leaderboard?sort={{.Metric}}&page={{.Next}}&perPage={{.PerPage}}">Next &gt;</a>
+            {{end}}
+        </div>
+    </body>
+</html>
+`
+
+// tournamentTemplate used to display series-wide info, through every MT
+const tournamentTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title> MT Overview </title>
+        <link rel="stylesheet" href="{{.ServiceUri}}/style.css">
+        <meta charset="UTF-8">
+    </head>
+    <body>
+        <table class="tournament"><tbody>
+            <tr>
+                <td class="tournament_title" colspan="2">
+                    {{if .LatestMT}}{{.LatestMT}}{{else}}Mystery Tournament{{end}}
+                </td>
+            </tr>
+            <tr>
+                <td class="stats_label">Entrants</td>
+                <td class="stats_field">{{.LatestEntrants}}</td>
+            </tr>
+            <tr>
+                <td class="stats_label">Returning Players</td>
+                <td class="stats_field">{{.ReturningPlayers}}</td>
+            </tr>
+            <tr>
+                <td class="stats_label">New Players</td>
+                <td class="stats_field">{{.NewPlayers}}</td>
+            </tr>
+            <tr>
+                <td class="stats_label">Unique Players (all MTs)</td>
+                <td class="stats_field">{{.UniquePlayers}}</td>
+            </tr>
+        </tbody></table>
+
+        {{if .MTs}}
+            <table class="tournament"><tbody>
+                <tr>
+                    <th class="stats_label">MT</th>
+                    <th class="stats_field">Entrants</th>
+                    <th class="stats_field">New</th>
+                </tr>
+                {{range .MTs}}
+                    <tr>
+                        <td class="stats_label">{{.Name}}</td>
+                        <td class="stats_field">{{.Entrants}}</td>
+                        <td class="stats_field">{{.NewPlayers}}</td>
+                    </tr>
+                {{end}}
+            </tbody></table>
+        {{end}}
+
+        {{if .Champions}}
+            <table class="tournament"><tbody>
+                <tr>
+                    <th class="stats_label">Past Champions</th>
+                    <th class="stats_field">Titles</th>
+                </tr>
+                {{range .Champions}}
+                    <tr>
+                        <td class="stats_label">{{.Username}}</td>
+                        <td class="stats_field">{{.Titles}}</td>
+                    </tr>
+                {{end}}
+            </tbody></table>
+        {{end}}
+    </body>
+</html>
+`
+
+// notOnSrlTemplate used when the player isn't registered on SRL
+const notOnSrlTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title> {{.Username}} - Not on SRL </title>
+        <link rel="stylesheet" href="{{.ServiceUri}}/style.css">
+        <meta charset="UTF-8">
+    </head>
+    <body>
+        <div class="user">
+            <span class="username">{{.Username}}</span>
+        </div>
+        <p class="stats"> Couldn't find this player on SpeedRunsLive. </p>
+    </body>
+</html>
+`
+
+// outageTemplate used when any service used to retrieve the player is
+// unavailable
+const outageTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title> {{.Username}} - Unavailable </title>
+        <link rel="stylesheet" href="{{.ServiceUri}}/style.css">
+        <meta charset="UTF-8">
+    </head>
+    <body>
+        <div class="user">
+            <span class="username">{{.Username}}</span>
+        </div>
+        <p class="stats"> This player's stats are unavailable right now. Please, try again later. </p>
+    </body>
+</html>
+`
+
+// noTokenTemplate used when the server hasn't been authorized to access the
+// MT Career spreadsheet
+const noTokenTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title> {{.Username}} - Unauthorized </title>
+        <link rel="stylesheet" href="{{.ServiceUri}}/style.css">
+        <meta charset="UTF-8">
+    </head>
+    <body>
+        <div class="user">
+            <span class="username">{{.Username}}</span>
+        </div>
+        <p class="stats"> The server must be authorized to access the MT Career spreadsheet. </p>
+    </body>
+</html>
+`
+
+// producerTemplate used to select who is on air
+const producerTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title> MT Title Card - Producer </title>
+        <meta charset="UTF-8">
+    </head>
+    <body>
+        <h1> On air </h1>
+
+        {{if eq (len .Players) 0 }}
+            <p> Nobody is on air. </p>
+        {{else}}
+            <p>
+                {{range $i, $p := .Players}}{{if $i}} vs {{end}}<b>{{$p}}</b>{{end}}
+                (<a href="{{.ServiceUri}}{{.Path}}" target="_blank">preview</a>)
+            </p>
+        {{end}}
+
+        <p> Show <a href="{{.ServiceUri}}/live" target="_blank">{{.ServiceUri}}/live</a> on stream. </p>
+
+        <form action="{{.ServiceUri}}/producer" method="post">
+            <div>
+                <label for="playerA">Player: </label>
+                <input type="text" name="playerA" id="playerA">
+            </div>
+            <div>
+                <label for="playerB">Opponent (optional): </label>
+                <input type="text" name="playerB" id="playerB">
+            </div>
+            <div>
+                <input type="submit" value="Put on air!">
+                <input type="submit" name="clear" value="Clear">
+            </div>
+        </form>
+    </body>
+</html>
+`
+
+// adminTemplate used to inspect the server's state
+const adminTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+    <head>
+        <title> MT Title Card - Admin </title>
+        <meta charset="UTF-8">
+    </head>
+    <body>
+        {{if .Message}}
+            <p><b>{{.Message}}</b></p>
+        {{end}}
+
+        <form action="{{.ServiceUri}}/admin/purge" method="post" style="display: inline">
+            <input type="submit" value="Purge the cache">
+        </form>
+        <form action="{{.ServiceUri}}/admin/reload" method="post" style="display: inline">
+            <input type="submit" value="Download the spreadsheet again">
+        </form>
+
+        <h1> OAuth token </h1>
+        {{if .Token.LocalFiles}}
+            <p> Not needed, since the data is read from local files </p>
+        {{else if .Token.ServiceAccount}}
+            <p> Authenticated as the service account {{.Token.ServiceAccount}} </p>
+        {{else if .Token.Present}}
+            <p>
+                Expires at {{.Token.Expiry.Format "2006-01-02 15:04:05 MST"}}
+                {{if .Token.Revoked}}(revoked, must be <a href="{{.ServiceUri}}/">generated</a> again){{else if .Token.Refreshable}}(renewed automatically){{else}}(must be <a href="{{.ServiceUri}}/">generated</a> again){{end}}
+            </p>
+        {{else}}
+            <p> Missing: {{.Token.Error}} (<a href="{{.ServiceUri}}/">generate it</a>) </p>
+        {{end}}
+
+        <h1> Cached players ({{len .Players}}) </h1>
+        <table>
+            <tr><th>Player</th><th>Age</th><th>State</th></tr>
+            {{range .Players}}
+                <tr>
+                    <td>{{.Username}}</td>
+                    <td>{{.Age}}</td>
+                    <td>{{if .Stale}}Stale{{else}}Fresh{{end}}</td>
+                </tr>
+            {{end}}
+        </table>
+
+        <h1> MT Career spreadsheet </h1>
+        {{if .Sheet.Updated.IsZero}}
+            <p> Not downloaded yet. </p>
+        {{else}}
+            <p> Downloaded {{.SheetAge}} ago. </p>
+        {{end}}
+        <table>
+            <tr><th>Range</th><th>Sheet</th><th>Rows</th></tr>
+            {{range .Sheet.Ranges}}
+                <tr>
+                    <td>{{.Name}}</td>
+                    <td>{{.SheetName}}</td>
+                    <td>{{if .Loaded}}{{.Rows}}{{else}}Not loaded{{end}}</td>
+                </tr>
+            {{end}}
+        </table>
+        <p> Standings columns to placements: {{range $i, $p := .Sheet.IdxToPlace}}{{if $i}}, {{end}}{{$i}} &rarr; {{$p}}{{end}} </p>
+
+        <h1> Recent upstream errors </h1>
+        {{if .Errors}}
+            <table>
+                <tr><th>When</th><th>Source</th><th>Error</th></tr>
+                {{range .Errors}}
+                    <tr>
+                        <td>{{.When.Format "2006-01-02 15:04:05"}}</td>
+                        <td>{{.Source}}</td>
+                        <td>{{.Error}}</td>
+                    </tr>
+                {{end}}
+            </table>
+        {{else}}
+            <p> None. </p>
+        {{end}}
     </body>
 </html>
 `
@@ -121,7 +702,12 @@ const renewTemplate = `
         {{else}}
             <h1> Oh no! Looks like the token must be renegerated! </h1>
 
-            <p> Click the link bellow to generate a new token, the submit it! </p>
+            {{if .Revoked}}
+                <p> Google rejected the current token, most likely because the access was revoked. </p>
+            {{end}}
+
+            <p> Click the link bellow to authorize the server. Google redirects you back here, and the token is saved automatically! </p>
+            <p> If the redirect can't reach the server, copy the code from the redirected URL and submit it bellow. </p>
 
             </br>
             </br>
@@ -130,6 +716,7 @@ const renewTemplate = `
             </br>
 
             <form action="" method="post">
+                <input type="hidden" name="state" value="{{.State}}">
                 <div>
                     <label for="token">Enter the generate token: </label>
                     <input type="text" name="token" id="token" required>
@@ -0,0 +1,159 @@
+package page
+
+import (
+    "bytes"
+    "fmt"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/config"
+    "html/template"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+)
+
+// Files, within a theme's directory, that override each of the default pages
+const (
+    playerPage = "player.html"
+    matchPage = "match.html"
+    svgPage = "card.svg"
+    leaderboardPage = "leaderboard.html"
+    tournamentPage = "tournament.html"
+)
+
+const (
+    // themeCss is the file, within a theme's directory, that overrides the
+    // default CSS
+    themeCss = "style.css"
+    // themeAssets is the directory, within a theme's directory, with every
+    // other file used by the theme (e.g., images and fonts)
+    themeAssets = "assets"
+)
+
+// themePage is a page that may be overridden by a theme
+type themePage struct {
+    // file, within the theme's directory, that overrides the page
+    file string
+    // source retrieves the template used if the theme doesn't override the
+    // page
+    source func() string
+}
+
+// themePages lists every page that may be overridden by a theme. Pages not
+// overridden by a theme use the ones set in the configuration.
+var themePages = []themePage {
+    {playerPage, func() string { return config.Get().PageTemplate(pageTemplate) }},
+    {matchPage, func() string { return config.Get().MatchPageTemplate(matchTemplate) }},
+    {svgPage, func() string { return config.Get().SvgTemplate(svgTemplate) }},
+    {leaderboardPage, func() string { return leaderboardTemplate }},
+    {tournamentPage, func() string { return tournamentTemplate }},
+    // The player isn't in the spreadsheet yet, so display it as a newcomer
+    {notInSheetPage, func() string { return config.Get().PageTemplate(pageTemplate) }},
+    {notOnSrlPage, func() string { return notOnSrlTemplate }},
+    {outagePage, func() string { return outageTemplate }},
+    {noTokenPage, func() string { return noTokenTemplate }},
+}
+
+// theme groups every template and style used to render pages
+type theme struct {
+    // name of the theme, empty for the default theme
+    name string
+    // pages maps the file of each page to its parsed template
+    pages map[string]*template.Template
+    // css is the theme's style sheet
+    css []byte
+    // assets is the directory with the theme's assets, if any
+    assets string
+}
+
+// readThemeFile reads a file from the theme's directory. If the file doesn't
+// exist (or if this is the default theme), nil is returned without any error.
+func readThemeFile(dir, file string) ([]byte, error) {
+    if dir == "" {
+        return nil, nil
+    }
+
+    data, err := ioutil.ReadFile(filepath.Join(dir, file))
+    if os.IsNotExist(err) {
+        return nil, nil
+    } else if err != nil {
+        return nil, errors.Wrap(err, fmt.Sprintf("Failed to read '%s'", file))
+    }
+    return data, nil
+}
+
+// loadTheme parses every page in the theme at dir. If dir is empty, the
+// default theme (as set in the configuration) is loaded instead.
+func loadTheme(name, dir string) (*theme, error) {
+    t := &theme {
+        name: name,
+        pages: map[string]*template.Template{},
+    }
+
+    for _, p := range themePages {
+        source := p.source()
+        data, err := readThemeFile(dir, p.file)
+        if err != nil {
+            return nil, err
+        } else if data != nil {
+            source = string(data)
+        }
+
+        // html/template also escapes SVG, so usernames can't inject markup
+        // into it
+        t.pages[p.file], err = template.New(p.file).Parse(source)
+        if err != nil {
+            return nil, errors.Wrap(err, fmt.Sprintf("Failed to parse '%s'", p.file))
+        }
+    }
+
+    css, err := readThemeFile(dir, themeCss)
+    if err != nil {
+        return nil, err
+    } else if css == nil {
+        var buf bytes.Buffer
+        config.Get().WriteCss(&buf, []byte(style))
+        css = buf.Bytes()
+    }
+    t.css = css
+
+    if dir != "" {
+        assets := filepath.Join(dir, themeAssets)
+        if st, err := os.Stat(assets); err == nil && st.IsDir() {
+            t.assets = assets
+        }
+    }
+
+    return t, nil
+}
+
+// loadThemes parses every theme within dir, each on its own sub-directory.
+// Themes that fail to load are returned separately, mapped to their error.
+func loadThemes(dir string) (map[string]*theme, map[string]error) {
+    themes := map[string]*theme{}
+    failed := map[string]error{}
+
+    if dir == "" {
+        return themes, failed
+    }
+
+    entries, err := ioutil.ReadDir(dir)
+    if err != nil {
+        failed[""] = errors.Wrap(err, "Failed to list the themes directory")
+        return themes, failed
+    }
+
+    for _, entry := range entries {
+        if !entry.IsDir() {
+            continue
+        }
+        name := entry.Name()
+        t, err := loadTheme(name, filepath.Join(dir, name))
+        if err != nil {
+            failed[name] = err
+        } else {
+            themes[name] = t
+        }
+    }
+
+    return themes, failed
+}
@@ -0,0 +1,79 @@
+package page
+
+import (
+    "github.com/SirGFM/MTTitleCard/config"
+    "io/ioutil"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+// writeFile creates a file, and every directory leading to it
+func writeFile(t *testing.T, path, content string) {
+    err := os.MkdirAll(filepath.Dir(path), 0755)
+    if err == nil {
+        err = ioutil.WriteFile(path, []byte(content), 0644)
+    }
+    if err != nil {
+        t.Fatalf("Failed to write '%s': %+v", path, err)
+    }
+}
+
+func TestThemes(t *testing.T) {
+    setupCache(t, 0, func(srlUsername, username string) (Player, error) {
+        var p Player
+        p.Data.Username = username
+        return p, nil
+    })
+
+    dir := t.TempDir()
+    writeFile(t, filepath.Join(dir, "dark", playerPage),
+            `dark {{.Username}} {{.ServiceUri}}/style.css`)
+    writeFile(t, filepath.Join(dir, "dark", themeCss), `body { color: red; }`)
+    writeFile(t, filepath.Join(dir, "dark", themeAssets, "bg.txt"), `background`)
+    writeFile(t, filepath.Join(dir, "broken", playerPage), `{{.Username`)
+    writeFile(t, filepath.Join(dir, "secret.txt"), `secret`)
+
+    cfg := config.Get()
+    cfg.ThemesDir = dir
+    config.LoadConfig(cfg)
+
+    var ps pageServer
+    _, err := newPageServer(&ps)
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+    if _, ok := ps.themeErrors["broken"]; !ok {
+        t.Fatalf("Expected theme 'broken' to fail: %+v", ps.themeErrors)
+    } else if len(ps.themes) != 1 {
+        t.Fatalf("Expected a single theme to be loaded: %+v", ps.themes)
+    }
+
+    for _, tc := range []struct {
+        path string
+        status int
+        body string
+    } {
+        {"/theme/dark/GFM", 200, "dark GFM /theme/dark/style.css"},
+        {"/GFM?theme=dark", 200, "dark GFM /theme/dark/style.css"},
+        {"/theme/dark/style.css", 200, "color: red"},
+        {"/theme/dark/assets/bg.txt", 200, "background"},
+        {"/theme/dark/assets/../../secret.txt", 400, ""},
+        {"/theme/dark/vs/A/B", 200, "<table class=\"match\""},
+        {"/theme/broken/GFM", 404, ""},
+        {"/GFM?theme=missing", 404, ""},
+        {"/style.css", 200, ".stats_label"},
+        {"/assets/bg.txt", 404, ""},
+    } {
+        w := httptest.NewRecorder()
+        ps.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+
+        if w.Code != tc.status {
+            t.Errorf("%s: expected status %d, got %d", tc.path, tc.status, w.Code)
+        } else if !strings.Contains(w.Body.String(), tc.body) {
+            t.Errorf("%s: expected '%s' in the response:\n%s", tc.path, tc.body, w.Body.String())
+        }
+    }
+}
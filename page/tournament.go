@@ -0,0 +1,97 @@
+package page
+
+import (
+    "fmt"
+    "github.com/pkg/errors"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "log"
+    "net/http"
+)
+
+// TournamentData maps the series-wide info into an structure understood by
+// the tournament template page
+type TournamentData struct {
+    mtcareers.Tournament
+    // LatestMT is the name of the latest tournament, if known
+    LatestMT string
+    ServiceUri string
+}
+
+// fetchTournament downloads the series-wide info from the MT Career
+// spreadsheet. It may be replaced on tests.
+var fetchTournament func() (mtcareers.Tournament, error) = downloadTournament
+
+// cachedTournament retrieves the series-wide info, downloading it only if it
+// isn't cached (or if it has expired)
+func cachedTournament() (mtcareers.Tournament, error) {
+    v, err := _tournament.get(func() (interface{}, error) {
+        t, err := fetchTournament()
+        return t, err
+    })
+    if err != nil {
+        return mtcareers.Tournament{}, err
+    }
+    return v.(mtcareers.Tournament), nil
+}
+
+// downloadTournament downloads the series-wide info from the MT Career
+// spreadsheet
+func downloadTournament() (mtcareers.Tournament, error) {
+    sh, err := getProvider()
+    if err != nil {
+        return mtcareers.Tournament{}, errors.Wrap(err, "Failed to retrieve MT Career spreadsheet to generate the tournament card")
+    }
+    err = sh.GetTourneyInfo()
+    if err != nil {
+        return mtcareers.Tournament{}, errors.Wrap(err, "Failed to get tourney info to generate the tournament card")
+    }
+    t, err := sh.GetTournament()
+    // XXX: if err == nil, errors.Wrap returns nil as well!
+    return t, errors.Wrap(err, "Failed to get series-wide info to generate the tournament card")
+}
+
+// lookupTournament downloads the series-wide info
+func (r *request) lookupTournament() (TournamentData, error) {
+    t, err := cachedTournament()
+    if err != nil {
+        _upstreamErrors.record("tournament", err)
+        return TournamentData{}, err
+    }
+
+    data := TournamentData {
+        Tournament: t,
+        ServiceUri: r.serviceUri,
+    }
+    if len(t.MTs) > 0 {
+        data.LatestMT = t.MTs[len(t.MTs)-1].Name
+    }
+    return data, nil
+}
+
+// getTournament fits the series-wide info into the tournament template and
+// return the resulting page
+func (r *request) getTournament() {
+    data, err := r.lookupTournament()
+    if err != nil {
+        serr := fmt.Sprintf("%+v", err)
+        status, _ := errorStatus(err)
+        http.Error(r.w, serr, status)
+        log.Print(serr)
+        return
+    }
+
+    r.render(tournamentPage, "text/html", data)
+}
+
+// getApiTournament returns the series-wide info as JSON
+func (r *request) getApiTournament() {
+    data, err := r.lookupTournament()
+    if err != nil {
+        log.Printf("%+v", err)
+        status, _ := errorStatus(err)
+        r.apiFail(status, err.Error())
+        return
+    }
+
+    r.writeJson(http.StatusOK, data)
+}
@@ -0,0 +1,60 @@
+package page
+
+import (
+    "encoding/json"
+    "github.com/SirGFM/MTTitleCard/mtcareers"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestTournamentPage(t *testing.T) {
+    old := fetchTournament
+    fetchTournament = func() (mtcareers.Tournament, error) {
+        return mtcareers.Tournament {
+            MTs: []mtcareers.MT {
+                {Name: "MT13", Entrants: 20, NewPlayers: 20},
+                {Name: "MT14", Entrants: 24, NewPlayers: 6},
+            },
+            LatestEntrants: 24,
+            UniquePlayers: 26,
+            ReturningPlayers: 18,
+            NewPlayers: 6,
+            Champions: []mtcareers.Champion {
+                {Username: "<b>Champ</b>", Titles: 2},
+            },
+        }, nil
+    }
+    _tournament.invalidate()
+    t.Cleanup(func() {
+        fetchTournament = old
+        _tournament.invalidate()
+    })
+
+    ps, err := New()
+    if err != nil {
+        t.Fatalf("Failed to create the server: %+v", err)
+    }
+
+    w := httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/tournament", nil))
+    body := w.Body.String()
+    if w.Code != 200 {
+        t.Fatalf("Expected 200, got %d: %s", w.Code, body)
+    }
+    for _, s := range []string{"MT14", "MT13", ">18<", ">26<", "&lt;b&gt;Champ&lt;/b&gt;"} {
+        if !strings.Contains(body, s) {
+            t.Errorf("Expected '%s' in the tournament card", s)
+        }
+    }
+
+    w = httptest.NewRecorder()
+    ps.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/tournament", nil))
+    var data TournamentData
+    err = json.Unmarshal(w.Body.Bytes(), &data)
+    if err != nil {
+        t.Fatalf("Failed to decode the tournament: %+v", err)
+    } else if data.LatestMT != "MT14" || data.NewPlayers != 6 || len(data.Champions) != 1 {
+        t.Errorf("Unexpected tournament: %+v", data)
+    }
+}
@@ -3,9 +3,11 @@ package srlprofile
 import (
     "bytes"
     "encoding/json"
+    goErrors "errors"
     "fmt"
     "github.com/pkg/errors"
     "github.com/SirGFM/MTTitleCard/config"
+    "github.com/SirGFM/MTTitleCard/metrics"
     "io"
     "io/ioutil"
     "log"
@@ -13,6 +15,9 @@ import (
     "time"
 )
 
+// ErrUserNotFound indicates that the user isn't registered on SRL
+var ErrUserNotFound error = goErrors.New("User not found on SRL")
+
 // Mapping for the 'stats' field in SRL's API
 type SrlStats struct {
     Rank int
@@ -52,11 +57,24 @@ type SrlApiProfile struct {
     Game SrlGame
 }
 
+// upstreamError reports whether a request failed because of the service
+// itself, as opposed to the requested resource (e.g., an unknown user)
+func upstreamError(resp *http.Response, err error) error {
+    if err != nil {
+        return err
+    } else if resp.StatusCode >= 500 {
+        return errors.New(resp.Status)
+    }
+    return nil
+}
+
 // getUserAvatar URL from Twitch's API
 func getUserAvatar(channel string) (string, error) {
     url := fmt.Sprintf("https://api.twitch.tv/kraken/channels/%s?client_id=%s",
             channel, config.Get().TwitchClientID)
+    start := time.Now()
     resp, err := http.Get(url)
+    metrics.Upstream(metrics.Twitch, start, upstreamError(resp, err))
     if err != nil {
         return "", errors.Wrap(err, "Failed to get twitch info")
     }
@@ -103,6 +121,11 @@ func decodeUser(dec *json.Decoder) (u User, err error) {
         return
     }
 
+    if api.Player.Name == "" {
+        err = errors.Wrap(ErrUserNotFound, "Empty player in the JSON")
+        return
+    }
+
     u.Name = api.Player.Name
     u.Channel = api.Player.Channel
     u.FirstRace = time.Unix(int64(api.Stats.FirstRaceDate), 0).Format("Jan 2, 2006")
@@ -128,12 +151,20 @@ func decodeUser(dec *json.Decoder) (u User, err error) {
 //   http://api.speedrunslive.com/stat?player=<username>
 func GetFromApi(url string) (User, error) {
     // Download the user data
+    start := time.Now()
     resp, err := http.Get(url)
+    metrics.Upstream(metrics.SRL, start, upstreamError(resp, err))
     if err != nil {
         return User{}, errors.Wrap(err, "Failed to get user from API")
     }
     defer resp.Body.Close()
 
+    if resp.StatusCode == http.StatusNotFound {
+        return User{}, errors.Wrap(ErrUserNotFound, "SRL's API didn't find the user")
+    } else if resp.StatusCode != http.StatusOK {
+        return User{}, errors.New(fmt.Sprintf("SRL's API failed with '%s'", resp.Status))
+    }
+
     buf, err := ioutil.ReadAll(resp.Body)
     if err != nil {
         return User{}, errors.Wrap(err, "Failed to read response from the API")
@@ -142,7 +173,7 @@ func GetFromApi(url string) (User, error) {
 
     // Try to decode with Go's builtin decoder
     dec := json.NewDecoder(reader)
-    if u, err := decodeUser(dec); err == nil {
+    if u, err := decodeUser(dec); err == nil || errors.Cause(err) == ErrUserNotFound {
         return u, err
     }
 